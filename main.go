@@ -56,10 +56,28 @@ func main() {
       return ctrl.Start(ctx)
     })
 
+    mode := cfg.GameMode
     if cfg.EnableSimulation {
+      mode = constants.GAME_MODE_SIMULATION
+    }
+
+    for _, arena := range ctrl.Arenas() {
+      arena := arena
       g.Go(func() error {
-        ctrl.NewGame(constants.GAME_MODE_SIMULATION)
-        return ctrl.StartGame(ctx)
+        // finish off a game the controller died in the middle of before starting anything new
+        resumed, err := ctrl.RecoverGame(ctx, arena)
+        if err != nil || resumed || mode == "" {
+          return err
+        }
+
+        if cfg.Rounds > 1 {
+          return ctrl.StartMatch(ctx, arena, mode)
+        }
+
+        if err := ctrl.NewGame(arena, mode); err != nil {
+          return err
+        }
+        return ctrl.StartGame(ctx, arena)
       })
     }
   }
@@ -0,0 +1,98 @@
+package common
+
+import (
+  "sync"
+  "time"
+)
+
+// Clock lets games run against real time, or a manual clock when they need to be reproduced
+type Clock interface {
+  Now() time.Time
+  Since(t time.Time) time.Duration
+  After(d time.Duration) <-chan time.Time
+}
+
+type RealClock struct {}
+
+func NewRealClock() *RealClock {
+  return &RealClock{}
+}
+
+func (c *RealClock) Now() time.Time {
+  return time.Now()
+}
+
+func (c *RealClock) Since(t time.Time) time.Duration {
+  return time.Since(t)
+}
+
+func (c *RealClock) After(d time.Duration) <-chan time.Time {
+  return time.After(d)
+}
+
+// ManualClock only moves when it is told to
+type ManualClock struct {
+  now           time.Time
+  waiters       []manualWaiter
+  lock          *sync.Mutex
+}
+
+type manualWaiter struct {
+  at            time.Time
+  c             chan time.Time
+}
+
+func NewManualClock(start time.Time) *ManualClock {
+  return &ManualClock{
+    now:      start,
+    waiters:  []manualWaiter{},
+    lock:     &sync.Mutex{},
+  }
+}
+
+func (c *ManualClock) Now() time.Time {
+  c.lock.Lock()
+  defer c.lock.Unlock()
+  return c.now
+}
+
+func (c *ManualClock) Since(t time.Time) time.Duration {
+  return c.Now().Sub(t)
+}
+
+// After fires once the clock has been moved at least d past now
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+  c.lock.Lock()
+  defer c.lock.Unlock()
+  w := manualWaiter{at: c.now.Add(d), c: make(chan time.Time, 1)}
+  c.waiters = append(c.waiters, w)
+  c.fire()
+  return w.c
+}
+
+func (c *ManualClock) Set(t time.Time) {
+  c.lock.Lock()
+  defer c.lock.Unlock()
+  c.now = t
+  c.fire()
+}
+
+func (c *ManualClock) Advance(d time.Duration) {
+  c.lock.Lock()
+  defer c.lock.Unlock()
+  c.now = c.now.Add(d)
+  c.fire()
+}
+
+// fire lets every waiter that is due go, the lock must be held
+func (c *ManualClock) fire() {
+  waiting := []manualWaiter{}
+  for _, w := range c.waiters {
+    if w.at.After(c.now) {
+      waiting = append(waiting, w)
+    } else {
+      w.c <- c.now
+    }
+  }
+  c.waiters = waiting
+}
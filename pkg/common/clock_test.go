@@ -0,0 +1,34 @@
+package common
+
+import (
+  "time"
+  "testing"
+)
+
+func TestManualClockAfter(t *testing.T) {
+  clock := NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+  after := clock.After(3 * time.Second)
+
+  clock.Advance(2 * time.Second)
+  select {
+    case <-after:
+      t.Fatalf("fired after 2s of 3s")
+    default:
+  }
+
+  clock.Advance(1 * time.Second)
+  select {
+    case at := <-after:
+      if !at.Equal(clock.Now()) {
+        t.Fatalf("fired at %s, not %s", at, clock.Now())
+      }
+    default:
+      t.Fatalf("did not fire after 3s")
+  }
+
+  select {
+    case <-clock.After(0):
+    default:
+      t.Fatalf("did not fire right away without a wait")
+  }
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+  "sync"
+)
+
+// Counter counts things by name, i.e. the events dropped because a channel was full
+type Counter struct {
+  counts        map[string]uint64
+  lock          *sync.Mutex
+}
+
+func NewCounter() *Counter {
+  return &Counter{
+    counts:     map[string]uint64{},
+    lock:       &sync.Mutex{},
+  }
+}
+
+func (c *Counter) Add(name string) {
+  c.lock.Lock()
+  defer c.lock.Unlock()
+  c.counts[name] += 1
+}
+
+// Counts is a copy of the counts, safe to hand out
+func (c *Counter) Counts() map[string]uint64 {
+  c.lock.Lock()
+  defer c.lock.Unlock()
+  counts := map[string]uint64{}
+  for name, count := range c.counts {
+    counts[name] = count
+  }
+  return counts
+}
@@ -2,6 +2,7 @@ package common
 
 import (
   "fmt"
+  "time"
   "errors"
   "strings"
   "strconv"
@@ -56,3 +57,172 @@ func ParseNodeHitPayload(payload []byte) (string, string, int, error) {
   return sensorid, sensorcolor, hitcount, nil
 }
 
+// ArenaEvent prefixes an event (or query) name with the arena it is for, no arena leaves it as is
+func ArenaEvent(arena, name string) string {
+  if arena == "" {
+    return name
+  }
+  return arena + constants.ARENA_SPLIT + name
+}
+
+// ParseArenaEvent splits the arena off an event (or query) name, the arena is empty if there was none
+func ParseArenaEvent(name string) (string, string) {
+  if arena, rest, ok := strings.Cut(name, constants.ARENA_SPLIT); ok {
+    return arena, rest
+  }
+  return "", name
+}
+
+// ParseStartAt parses the agreed start time of a countdown, sent in unix milliseconds
+func ParseStartAt(payload []byte) (time.Time, error) {
+  ms, err := strconv.ParseInt(string(payload), 10, 64)
+  if err != nil {
+    return time.Time{}, errors.New(fmt.Sprintf("cannot parse start time from %s - %s", string(payload), err))
+  }
+  return time.UnixMilli(ms), nil
+}
+
+// ParseHitReport parses a hit pushed by a node, the team is empty when nobody was credited
+func ParseHitReport(payload []byte) (string, string, string, uint64, string, string, int, int, error) {
+  parts := ParsePayload(payload)
+
+  // <node>:<game-id>:<run>:<seq>:<sensor-id>:<team>:<hits>:<points>
+  if len(parts) != 8 {
+    return "", "", "", 0, "", "", 0, 0, constants.ERR_INVALID_HIT_REPORT
+  }
+
+  seq, err := strconv.ParseUint(parts[3], 10, 64)
+  if err != nil {
+    return "", "", "", 0, "", "", 0, 0, errors.New(fmt.Sprintf("cannot parse hit sequence number from %s - %s", string(payload), err))
+  }
+
+  hits, err := ParseInt(parts[6])
+  if err != nil {
+    return "", "", "", 0, "", "", 0, 0, errors.New(fmt.Sprintf("cannot parse hit count from %s - %s", string(payload), err))
+  }
+
+  points, err := ParseInt(parts[7])
+  if err != nil {
+    return "", "", "", 0, "", "", 0, 0, errors.New(fmt.Sprintf("cannot parse hit points from %s - %s", string(payload), err))
+  }
+
+  return parts[0], parts[1], parts[2], seq, parts[4], parts[5], hits, points, nil
+}
+
+// ParseHitRecordsQuery parses which page of a game's hit records the controller wants
+func ParseHitRecordsQuery(payload []byte) (string, int, error) {
+  parts := ParsePayload(payload)
+
+  // <game-id>:<offset>
+  if len(parts) != 2 {
+    return "", 0, constants.ERR_INVALID_HIT_RECORDS_QUERY
+  }
+
+  offset, err := ParseInt(parts[1])
+  if err != nil || offset < 0 {
+    return "", 0, constants.ERR_INVALID_HIT_RECORDS_QUERY
+  }
+
+  return parts[0], offset, nil
+}
+
+// ParseSelfTestResult parses which sensors of a node passed a self test
+func ParseSelfTestResult(payload []byte) (string, map[string]bool, error) {
+  node, results, ok := strings.Cut(string(payload), constants.SPLIT)
+  if !ok || node == "" {
+    return "", nil, constants.ERR_INVALID_SELFTEST_RESULT
+  }
+
+  // <node>:<sensor-id>=<pass|fail>,...
+  sensors := map[string]bool{}
+  for _, result := range strings.Split(results, constants.COMMA) {
+    if result == "" {
+      continue // a node without sensors
+    }
+
+    id, passfail, ok := strings.Cut(result, "=")
+    if !ok || (passfail != constants.SELFTEST_PASS && passfail != constants.SELFTEST_FAIL) {
+      return "", nil, constants.ERR_INVALID_SELFTEST_RESULT
+    }
+    sensors[id] = passfail == constants.SELFTEST_PASS
+  }
+
+  return node, sensors, nil
+}
+
+func ParseTargetHit(payload []byte) (string, string, string, int64, error) {
+  parts := ParsePayload(payload)
+
+  // <node>:<sensor-id>:<sensor-color>:<reaction-ms>
+  if len(parts) != 4 {
+    return "", "", "", 0, constants.ERR_INVALID_TARGET_HIT
+  }
+
+  reaction, err := strconv.ParseInt(parts[3], 10, 64)
+  if err != nil {
+    return "", "", "", 0, errors.New(fmt.Sprintf("cannot parse target reaction time from %s - %s", string(payload), err))
+  }
+
+  return parts[0], parts[1], parts[2], reaction, nil
+}
+
+func ParseTargetDamage(payload []byte) (string, string, string, error) {
+  parts := ParsePayload(payload)
+
+  // <node>:<sensor-id>:<sensor-color>
+  if len(parts) != 3 {
+    return "", "", "", constants.ERR_INVALID_TARGET_DAMAGE
+  }
+
+  return parts[0], parts[1], parts[2], nil
+}
+
+func ParseTargetCapture(payload []byte) (string, string, string, error) {
+  parts := ParsePayload(payload)
+
+  // <node>:<sensor-id>:<team>
+  if len(parts) != 3 {
+    return "", "", "", constants.ERR_INVALID_TARGET_CAPTURE
+  }
+
+  return parts[0], parts[1], parts[2], nil
+}
+
+func ParseTargetMiss(payload []byte) (string, string, error) {
+  parts := ParsePayload(payload)
+
+  // <node>:<sensor-id>
+  if len(parts) != 2 {
+    return "", "", constants.ERR_INVALID_TARGET_MISS
+  }
+
+  return parts[0], parts[1], nil
+}
+
+func ParseSetTarget(payload []byte) (string, string, string, error) {
+  parts := ParsePayload(payload)
+
+  // <node>:<sensor-id>:<color>
+  if len(parts) != 3 {
+    return "", "", "", constants.ERR_INVALID_SET_TARGET
+  }
+
+  return parts[0], parts[1], parts[2], nil
+}
+
+func ParseGameReset(payload []byte) (string, int64, bool, error) {
+  parts := ParsePayload(payload)
+
+  // <game-id>:<seed>[:resume]
+  if len(parts) < 2 || len(parts) > 3 {
+    return "", 0, false, constants.ERR_INVALID_GAME_RESET
+  }
+
+  seed, err := strconv.ParseInt(parts[1], 10, 64)
+  if err != nil {
+    return "", 0, false, err
+  }
+
+  resume := len(parts) == 3 && parts[2] == constants.GAME_RESUME_ID
+  return parts[0], seed, resume, nil
+}
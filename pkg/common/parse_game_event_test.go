@@ -0,0 +1,89 @@
+package common
+
+import (
+  "fmt"
+  "time"
+  "testing"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+func TestParseHitReport(t *testing.T) {
+  tests := []struct {
+    payload   string
+    node      string
+    gameid    string
+    run       string
+    seq       uint64
+    sensorid  string
+    team      string
+    hits      int
+    points    int
+    ok        bool
+  }{
+    {"node1:game-1:run-1:7:one:red:1:2", "node1", "game-1", "run-1", 7, "one", "red", 1, 2, true},
+    {"node1:game-1:run-1:8:one::1:0", "node1", "game-1", "run-1", 8, "one", "", 1, 0, true},       // nobody credited
+    {"node2:game-1:run-2:1:civ:blue:1:-5", "node2", "game-1", "run-2", 1, "civ", "blue", 1, -5, true},
+    {"node1:game-1:run-1:7:one:red:1", "", "", "", 0, "", "", 0, 0, false},                         // too short
+    {"node1:game-1:run-1:7:one:red:1:2:3", "", "", "", 0, "", "", 0, 0, false},                     // too long
+    {"node1:game-1:run-1:-1:one:red:1:2", "", "", "", 0, "", "", 0, 0, false},                      // negative seq
+    {"node1:game-1:run-1:x:one:red:1:2", "", "", "", 0, "", "", 0, 0, false},
+    {"node1:game-1:run-1:7:one:red:x:2", "", "", "", 0, "", "", 0, 0, false},
+    {"node1:game-1:run-1:7:one:red:1:x", "", "", "", 0, "", "", 0, 0, false},
+    {"", "", "", "", 0, "", "", 0, 0, false},
+  }
+
+  for _, tt := range tests {
+    node, gameid, run, seq, sensorid, team, hits, points, err := ParseHitReport([]byte(tt.payload))
+    if tt.ok != (err == nil) {
+      t.Errorf("%q: error %v", tt.payload, err)
+      continue
+    }
+    if node != tt.node || gameid != tt.gameid || run != tt.run || seq != tt.seq || sensorid != tt.sensorid || team != tt.team || hits != tt.hits || points != tt.points {
+      t.Errorf("%q: parsed %s %s %s %d %s %q %d %d", tt.payload, node, gameid, run, seq, sensorid, team, hits, points)
+    }
+  }
+
+  if _, _, _, _, _, _, _, _, err := ParseHitReport([]byte("a:b")); err != constants.ERR_INVALID_HIT_REPORT {
+    t.Errorf("got %v for a short payload, want %s", err, constants.ERR_INVALID_HIT_REPORT)
+  }
+}
+
+func TestParseStartAt(t *testing.T) {
+  startat := time.Date(2024, 1, 1, 12, 0, 5, 250 * int(time.Millisecond), time.UTC)
+  got, err := ParseStartAt([]byte(fmt.Sprintf("%d", startat.UnixMilli())))
+  if err != nil {
+    t.Fatalf("cannot parse start time: %s", err)
+  }
+  if !got.Equal(startat) {
+    t.Fatalf("parsed start time %s, want %s", got, startat)
+  }
+
+  for _, payload := range []string{"", "soon", "12.5"} {
+    if _, err := ParseStartAt([]byte(payload)); err == nil {
+      t.Errorf("no error parsing start time %q", payload)
+    }
+  }
+}
+
+func TestParseHitRecordsQuery(t *testing.T) {
+  tests := []struct {
+    payload   string
+    gameid    string
+    offset    int
+    ok        bool
+  }{
+    {"game-1:0", "game-1", 0, true},
+    {"game-1:25", "game-1", 25, true},
+    {"game-1:-1", "", 0, false},
+    {"game-1:x", "", 0, false},
+    {"game-1", "", 0, false},
+    {"game-1:1:2", "", 0, false},
+  }
+
+  for _, tt := range tests {
+    gameid, offset, err := ParseHitRecordsQuery([]byte(tt.payload))
+    if tt.ok != (err == nil) || gameid != tt.gameid || offset != tt.offset {
+      t.Errorf("%q: parsed %q %d %v", tt.payload, gameid, offset, err)
+    }
+  }
+}
@@ -0,0 +1,16 @@
+package common
+
+import (
+  "time"
+  "math/rand"
+)
+
+// NewSeed picks a seed for games that were not given one
+func NewSeed() int64 {
+  return time.Now().UnixNano()
+}
+
+// NewRand returns a random source that replays the same values for the same seed
+func NewRand(seed int64) *rand.Rand {
+  return rand.New(rand.NewSource(seed))
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+  "fmt"
+  "slices"
+  "strings"
+  "path/filepath"
+  "gopkg.in/yaml.v2"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// ArenasConfig splits the nodes into arenas that each play their own games, a node joins one with -tag arena=<name>
+type ArenasConfig struct {
+  Arenas        map[string][]string   `yaml:"arenas" json:"arenas"` // the expected nodes of each arena
+}
+
+func NewArenasConfig() *ArenasConfig {
+  return &ArenasConfig{
+    Arenas:       map[string][]string{},
+  }
+}
+
+// Set parses an -arena flag in the form <arena>=<node>,<node>
+func (ac *ArenasConfig) Set(value string) error {
+  name, nodes, ok := strings.Cut(value, "=")
+  if !ok || name == "" || nodes == "" || strings.Contains(name, constants.ARENA_SPLIT) {
+    return constants.ERR_INVALID_ARENA_FLAG
+  }
+
+  ac.Arenas[name] = strings.Split(nodes, constants.COMMA)
+  return nil
+}
+
+// Names lists the arenas in sorted order, the first being the default arena
+func (ac *ArenasConfig) Names() []string {
+  names := []string{}
+  for name, _ := range ac.Arenas {
+    names = append(names, name)
+  }
+  slices.Sort(names)
+  return names
+}
+
+func (ac *ArenasConfig) String() string {
+  yamlBytes, err := yaml.Marshal(ac)
+  if err != nil {
+    return fmt.Sprintf("Error marshalling arenas config into yaml: %s", err)
+  }
+  return string(yamlBytes)
+}
+
+// ForArena is a copy of the config for a single arena, with the arena's nodes and its own log directory
+func (c *Config) ForArena(name string) *Config {
+  cfg := *c
+  cfg.Arena = name
+  if nodes, ok := c.ArenasConf.Arenas[name]; ok {
+    cfg.Nodes = nodes
+  }
+  if c.Logdir != "" && name != "" {
+    cfg.Logdir = filepath.Join(c.Logdir, name)
+  }
+  return &cfg
+}
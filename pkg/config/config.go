@@ -4,6 +4,7 @@ import (
   "os"
   "fmt"
   "log"
+  "time"
   "slices"
   "strings"
 
@@ -35,22 +36,70 @@ type Config struct {
   AgentConf               *agent.Config   `yaml:"-" json:"-"`
   SerfConf                *serf.Config    `yaml:"-" json:"-"`
   SensorsConf             *SensorsConfig  `yaml:"sensors" json:"sensors"`
+  HandicapsConf           *HandicapsConfig `yaml:"handicaps" json:"handicaps"`
+  PointsConf              *PointsConfig   `yaml:"points" json:"points"`
+  ArenasConf              *ArenasConfig   `yaml:"arenas" json:"arenas"`
+  Arena                   string          `yaml:"-" json:"-"` // the arena a copy of the config is for (see ForArena)
   Coalesce                bool            `yaml:"coalesce" json:"coalesce"`
   JoinAddrs               []string        `yaml:"join_addrs" json:"join_addrs"`
 
   // game config
+  GameMode                string          `yaml:"game_mode" json:"game_mode"`
+  ModesDir                string          `yaml:"modes_dir" json:"modes_dir"`
   WinningScore            int             `yaml:"winning_score" json:"winning_score"`
   GameLength              string          `yaml:"game_length" json:"game_length"`
+  HillInterval            string          `yaml:"hill_interval" json:"hill_interval"`
+  TargetTimeout           string          `yaml:"target_timeout" json:"target_timeout"`
+  TargetHealth            int             `yaml:"target_health" json:"target_health"`
+  CaptureBonus            int             `yaml:"capture_bonus" json:"capture_bonus"`
+  DrillTargets            int             `yaml:"drill_targets" json:"drill_targets"`
+  Player                  string          `yaml:"player" json:"player"`
+  TieBreak                string          `yaml:"tie_break" json:"tie_break"`
+  Overtime                string          `yaml:"overtime" json:"overtime"`
+  Rounds                  int             `yaml:"rounds" json:"rounds"`
+  Course                  string          `yaml:"course" json:"course"`
+  PenaltySeconds          int             `yaml:"penalty_seconds" json:"penalty_seconds"`
+  Countdown               int             `yaml:"countdown" json:"countdown"`
+  MinNodes                int             `yaml:"min_nodes" json:"min_nodes"` // limits of 0 are left to the game mode
+  MaxNodes                int             `yaml:"max_nodes" json:"max_nodes"`
+  MinTeams                int             `yaml:"min_teams" json:"min_teams"`
+  MaxTeams                int             `yaml:"max_teams" json:"max_teams"`
+  RequiredNodes           []string        `yaml:"required_nodes" json:"required_nodes"`
+  RequiredTeams           []string        `yaml:"required_teams" json:"required_teams"`
+  Civilians               []string        `yaml:"civilians" json:"civilians"` // <node> or <node>:<sensor-id> that must not be hit
+  CivilianPoints          int             `yaml:"civilian_points" json:"civilian_points"`
+  StreakHits              int             `yaml:"streak_hits" json:"streak_hits"` // 0 turns streaks off
+  StreakWindow            string          `yaml:"streak_window" json:"streak_window"`
+  StreakBonus             int             `yaml:"streak_bonus" json:"streak_bonus"`
+  Seed                    int64           `yaml:"seed" json:"seed"`
+  Recovery                string          `yaml:"recovery" json:"recovery"`
+  RoundBreak              string          `yaml:"round_break" json:"round_break"`
+  HealthInterval          string          `yaml:"health_interval" json:"health_interval"` // 0 turns node health checks off
+  SelfTestWindow          string          `yaml:"selftest_window" json:"selftest_window"` // how long a self test waits for a hit on each sensor
 
   // server config
   WebAddr                 string          `yaml:"web_addr" json:"web_addr"`
 
   Timeout                 int             `yaml:"timeout" json:"timeout"`
   Logdir                  string          `yaml:"logdir" json:"logdir"`
+  JournalDir              string          `yaml:"journal_dir" json:"journal_dir"` // where nodes journal their hits, empty keeps them in memory only
+  SensorsFile             string          `yaml:"sensors_file" json:"sensors_file"` // where nodes save sensors changed by the controller
   ConfigFile              string          `yaml:"config_file" json:"config_file"`
   *log.Logger                             `yaml:"-" json:"-"`
 }
 
+// ForGame is a copy of the config for a single game with other teams and/or game length, empty keeps the current
+func (c *Config) ForGame(teams []string, gamelength string) *Config {
+  cfg := *c
+  if len(teams) > 0 {
+    cfg.Teams = teams
+  }
+  if gamelength != "" {
+    cfg.GameLength = gamelength
+  }
+  return &cfg
+}
+
 func NewConfig(logger *log.Logger) *Config {
   ac := agent.DefaultConfig()
   sc := serf.DefaultConfig()
@@ -81,14 +130,50 @@ func NewConfig(logger *log.Logger) *Config {
     AgentConf:          ac,
     SerfConf:           sc,
     SensorsConf:        NewSensorsConfig(),
+    HandicapsConf:      NewHandicapsConfig(),
+    PointsConf:         NewPointsConfig(),
+    ArenasConf:         NewArenasConfig(),
+    Arena:              "",
     Coalesce:           false,
     JoinAddrs:          strings.Split(joinaddrs, ","),
+    GameMode:           "",
+    ModesDir:           "",
     WinningScore:       10,
     GameLength:         "3m",
+    HillInterval:       "1m",
+    TargetTimeout:      "10s",
+    TargetHealth:       5,
+    CaptureBonus:       3,
+    DrillTargets:       20,
+    Player:             "",
+    TieBreak:           constants.TIE_BREAK_DRAW,
+    Overtime:           "1m",
+    Rounds:             1,
+    Course:             "",
+    PenaltySeconds:     5,
+    Countdown:          5,
+    MinNodes:           0,
+    MaxNodes:           0,
+    MinTeams:           0,
+    MaxTeams:           0,
+    RequiredNodes:      []string{},
+    RequiredTeams:      []string{},
+    Civilians:          []string{},
+    CivilianPoints:     -5,
+    StreakHits:         0,
+    StreakWindow:       "5s",
+    StreakBonus:        3,
+    Seed:               0,
+    Recovery:           constants.RECOVERY_ASK,
+    RoundBreak:         "30s",
+    HealthInterval:     "30s",
+    SelfTestWindow:     "5s",
     WebAddr:            ":8080",
     Timeout:            10, // 10 second timeouts
     ConfigFile:         "",
     Logdir:             "/data/logs",
+    JournalDir:         "/data/journal",
+    SensorsFile:        "/data/sensors.yaml",
     Logger:             log.New(logger.Writer(), "[CONFIG]: ", logger.Flags()),
   }
 }
@@ -98,6 +183,30 @@ func (c *Config) Validate() error {
   c.Teams = slices.CompactFunc(c.Teams, strings.EqualFold)
   c.Colors = slices.CompactFunc(c.Colors, strings.EqualFold)
 
+  switch c.TieBreak {
+    case constants.TIE_BREAK_DRAW, constants.TIE_BREAK_OVERTIME, constants.TIE_BREAK_SUDDEN_DEATH:
+    default:
+      return constants.ERR_INVALID_TIE_BREAK
+  }
+
+  if _, err := time.ParseDuration(c.StreakWindow); err != nil {
+    return err
+  }
+
+  if _, err := time.ParseDuration(c.HealthInterval); err != nil {
+    return err
+  }
+
+  if _, err := time.ParseDuration(c.SelfTestWindow); err != nil {
+    return err
+  }
+
+  switch c.Recovery {
+    case constants.RECOVERY_ASK, constants.RECOVERY_RESUME, constants.RECOVERY_ABORT:
+    default:
+      return constants.ERR_INVALID_RECOVERY
+  }
+
   ac := c.AgentConf
   sc := c.SerfConf
 
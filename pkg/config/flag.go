@@ -35,6 +35,49 @@ func (c *Config) Flags() error {
   flag.IntVar(&c.Timeout, "timeout", c.Timeout, "number of seconds to wait to timeout nodes/connections/etc")
   flag.StringVar(&c.WebAddr, "web-addr", c.WebAddr, "The web address to have the controller server listen on")
   flag.StringVar(&c.Logdir, "logdir", c.Logdir, "The directory to store game logs (which are served from the UI)")
+  flag.StringVar(&c.JournalDir, "journal-dir", c.JournalDir, "The directory nodes journal their hits in, to count them after a restart (empty to keep them in memory only)")
+  flag.StringVar(&c.SensorsFile, "sensors-file", c.SensorsFile, "The file nodes save sensor changes from the controller in, they are applied on top of the -sensor flags at start (empty to not save them)")
+  flag.StringVar(&c.GameMode, "game-mode", c.GameMode, "The game mode to start once the controller is up (ignored if -enable-simulation is set)")
+  flag.StringVar(&c.ModesDir, "modes-dir", c.ModesDir, "A directory of yaml game mode definitions to load, each usable as a -game-mode by its name")
+  flag.StringVar(&c.GameLength, "game-length", c.GameLength, "How long games last (i.e. 3m)")
+  flag.IntVar(&c.WinningScore, "winning-score", c.WinningScore, "The score a team needs to win a game")
+  flag.IntVar(&c.MinNodes, "min-nodes", c.MinNodes, "The fewest nodes a game can be played with (0 leaves it to the game mode)")
+  flag.IntVar(&c.MaxNodes, "max-nodes", c.MaxNodes, "The most nodes a game can be played with (0 leaves it to the game mode)")
+  flag.IntVar(&c.MinTeams, "min-teams", c.MinTeams, "The fewest teams a game can be played with (0 leaves it to the game mode)")
+  flag.IntVar(&c.MaxTeams, "max-teams", c.MaxTeams, "The most teams a game can be played with (0 leaves it to the game mode)")
+  flag.Var((*AppendSliceValue)(&c.RequiredNodes), "require-node", "add a node that must be in every game")
+  flag.Var((*AppendSliceValue)(&c.RequiredTeams), "require-team", "add a team that must be in every game")
+  flag.StringVar(&c.HillInterval, "hill-interval", c.HillInterval, "How often the hill moves to another node in king of the hill games (i.e. 1m)")
+  flag.StringVar(&c.TargetTimeout, "target-timeout", c.TargetTimeout, "How long a target stays lit before it moves in whack-a-mole games (i.e. 10s)")
+  flag.IntVar(&c.TargetHealth, "target-health", c.TargetHealth, "The number of hits each sensor can take before it is eliminated in elimination games")
+  flag.IntVar(&c.CaptureBonus, "capture-bonus", c.CaptureBonus, "The bonus points for the team that eliminates a sensor in elimination games")
+  flag.IntVar(&c.DrillTargets, "drill-targets", c.DrillTargets, "The number of targets to hit in solo drill games")
+  flag.StringVar(&c.Player, "player", c.Player, "The name of the player running a solo drill (saved in the game log)")
+  flag.StringVar(&c.TieBreak, "tie-break", c.TieBreak, "What to do when a game ends level - draw, overtime or sudden-death")
+  flag.IntVar(&c.Rounds, "rounds", c.Rounds, "Play the game mode as a best-of match with this many rounds (1 plays a single game)")
+  flag.StringVar(&c.RoundBreak, "round-break", c.RoundBreak, "How long to wait between rounds of a match, or between teams in a time trial (i.e. 30s)")
+  flag.StringVar(&c.HealthInterval, "health-interval", c.HealthInterval, "How often the controller checks the health of the nodes and their sensors (0 to turn off)")
+  flag.StringVar(&c.SelfTestWindow, "selftest-window", c.SelfTestWindow, "How long a node self test waits for a test hit on each sensor (i.e. 5s)")
+  flag.StringVar(&c.Course, "course", c.Course, "The ordered targets of a time trial course in the form of -course node1:one,node2:two,node1:three")
+  flag.StringVar(&c.Recovery, "recovery", c.Recovery, "What to do with a game left unfinished when the controller died - ask (wait for the UI), resume or abort")
+  flag.Int64Var(&c.Seed, "seed", c.Seed, "Seed the randomness of each game to replay it exactly, the seed of every game is saved in its log (0 picks a new seed per game)")
+  flag.IntVar(&c.Countdown, "countdown", c.Countdown, "The seconds every node counts down before a game starts, 0 starts right away")
+  flag.IntVar(&c.PenaltySeconds, "penalty-seconds", c.PenaltySeconds, "The seconds added to a time trial for each out of order hit")
+  flag.StringVar(&c.Overtime, "overtime", c.Overtime, "How long overtime (or sudden death) lasts before a tied game is declared a draw (i.e. 1m)")
+
+  // -handicap red:5:1.5:8
+  flag.Var(c.HandicapsConf, "handicap", "Give a team a handicap in the form of -handicap red:5:1.5:8, <team>:<starting-bonus>[:<hit-multiplier>[:<winning-score>]]")
+
+  // -arena north=node1,node2 -arena south=node3,node4
+  flag.Var(c.ArenasConf, "arena", "Run an arena of its own games on the controller in the form of -arena north=node1,node2, the nodes join it with -tag arena=north")
+
+  // -points node3=2 -points node1:one=5
+  flag.Var(c.PointsConf, "points", "Set what a hit is worth in the form of -points node1:one=5, <node>[:<sensor-id>]=<points>")
+  flag.Var((*AppendSliceValue)(&c.Civilians), "civilian", "add a civilian target that costs points when hit, by <node> or <node>:<sensor-id>")
+  flag.IntVar(&c.CivilianPoints, "civilian-points", c.CivilianPoints, "The points a hit on a civilian target is worth (should be negative)")
+  flag.IntVar(&c.StreakHits, "streak-hits", c.StreakHits, "The hits in a row a team needs for a streak bonus, each within -streak-window of the last (0 turns streaks off)")
+  flag.StringVar(&c.StreakWindow, "streak-window", c.StreakWindow, "How soon a team's next hit must land to keep a streak going (i.e. 5s)")
+  flag.IntVar(&c.StreakBonus, "streak-bonus", c.StreakBonus, "The bonus points for every -streak-hits hits in a row")
 
   // -sensor 1:orangepi:gpiochip0:73:3
   flag.Var(c.SensorsConf, "sensor", "Add a sensor in the form of -sensor one:orangepi:gpiochip0:73:13, <1-4>:<device>:<gpiochip>:<hitpin>:<ledpin:?5vpin>")
@@ -59,6 +102,11 @@ func (c *Config) Flags() error {
 
   if c.EnableNode {
     c.AddNode(c.AgentConf.NodeName)
+
+    if err := c.LoadSensors(); err != nil {
+      c.Printf("error reading sensors file: %s", c.SensorsFile)
+      return err
+    }
   }
 
   if c.EnableController {
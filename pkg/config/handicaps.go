@@ -0,0 +1,91 @@
+package config
+
+import (
+  "fmt"
+  "math"
+  "strconv"
+  "strings"
+  "gopkg.in/yaml.v2"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+  "github.com/taemon1337/arena-nerf/pkg/common"
+)
+
+// Handicap evens out a game for a team, i.e. a younger team can start ahead or score more per hit
+type Handicap struct {
+  Team          string      `yaml:"team" json:"team"`
+  Bonus         int         `yaml:"bonus" json:"bonus"`                 // points the team starts with
+  Multiplier    float64     `yaml:"multiplier" json:"multiplier"`       // applied to every hit the team scores
+  WinningScore  int         `yaml:"winning_score" json:"winning_score"` // overrides the game winning score for the team
+}
+
+type HandicapsConfig struct {
+  Handicaps     map[string]*Handicap    `yaml:"handicaps" json:"handicaps"`
+}
+
+func NewHandicap(team string) *Handicap {
+  return &Handicap{
+    Team:         team,
+    Bonus:        0,
+    Multiplier:   1,
+    WinningScore: 0,
+  }
+}
+
+func NewHandicapsConfig() *HandicapsConfig {
+  return &HandicapsConfig{
+    Handicaps:    map[string]*Handicap{},
+  }
+}
+
+// Score applies the handicap to the number of points the team scored from hits
+func (h *Handicap) Score(points int) int {
+  return int(math.Round(float64(points) * h.Multiplier)) + h.Bonus
+}
+
+// Set parses a -handicap flag in the form <team>:<bonus>[:<multiplier>[:<winning-score>]], any part can be left empty
+func (hc *HandicapsConfig) Set(value string) error {
+  parts := strings.Split(value, constants.SPLIT)
+  if len(parts) < 2 || len(parts) > 4 || parts[0] == "" {
+    return constants.ERR_INVALID_HANDICAP_FLAG
+  }
+
+  h := NewHandicap(parts[0])
+
+  if parts[1] != "" {
+    bonus, err := common.ParseInt(parts[1])
+    if err != nil {
+      return err
+    }
+    h.Bonus = bonus
+  }
+
+  if len(parts) > 2 && parts[2] != "" {
+    multiplier, err := strconv.ParseFloat(parts[2], 64)
+    if err != nil {
+      return err
+    }
+    if multiplier <= 0 {
+      return constants.ERR_INVALID_HANDICAP_FLAG
+    }
+    h.Multiplier = multiplier
+  }
+
+  if len(parts) > 3 && parts[3] != "" {
+    score, err := common.ParseInt(parts[3])
+    if err != nil {
+      return err
+    }
+    h.WinningScore = score
+  }
+
+  hc.Handicaps[h.Team] = h
+  return nil
+}
+
+func (hc *HandicapsConfig) String() string {
+  yamlBytes, err := yaml.Marshal(hc)
+  if err != nil {
+    return fmt.Sprintf("Error marshalling handicaps config into yaml: %s", err)
+  }
+  return string(yamlBytes)
+}
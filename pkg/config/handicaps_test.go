@@ -0,0 +1,56 @@
+package config
+
+import (
+  "testing"
+)
+
+func TestHandicapScore(t *testing.T) {
+  tests := []struct {
+    bonus       int
+    multiplier  float64
+    points      int
+    want        int
+  }{
+    {0, 1, 7, 7},
+    {5, 1, 0, 5},      // the bonus counts before any hit
+    {5, 1.5, 4, 11},
+    {0, 1.5, 3, 5},    // 4.5 rounds up
+    {0, 1.5, 1, 2},    // 1.5 rounds up
+    {0, 0.5, 5, 3},    // 2.5 rounds away from zero
+    {0, 0.4, 1, 0},    // 0.4 rounds down
+    {2, 2, -3, -4},    // civilian hits cost more too
+  }
+
+  for _, tt := range tests {
+    h := NewHandicap("red")
+    h.Bonus = tt.bonus
+    h.Multiplier = tt.multiplier
+    if got := h.Score(tt.points); got != tt.want {
+      t.Errorf("%d points with bonus %d and multiplier %v scored %d, want %d", tt.points, tt.bonus, tt.multiplier, got, tt.want)
+    }
+  }
+}
+
+func TestHandicapsConfigSet(t *testing.T) {
+  hc := NewHandicapsConfig()
+  for _, flag := range []string{"red:5:1.5:8", "blue::2"} {
+    if err := hc.Set(flag); err != nil {
+      t.Fatalf("cannot set -handicap %s: %s", flag, err)
+    }
+  }
+
+  red := hc.Handicaps["red"]
+  if red == nil || red.Bonus != 5 || red.Multiplier != 1.5 || red.WinningScore != 8 {
+    t.Errorf("red handicap %+v, want bonus 5 multiplier 1.5 winning score 8", red)
+  }
+  blue := hc.Handicaps["blue"]
+  if blue == nil || blue.Bonus != 0 || blue.Multiplier != 2 || blue.WinningScore != 0 {
+    t.Errorf("blue handicap %+v, want bonus 0 multiplier 2", blue)
+  }
+
+  for _, flag := range []string{"red", ":5", "red:x", "red:1:y", "red:1:1:1:1"} {
+    if err := NewHandicapsConfig().Set(flag); err == nil {
+      t.Errorf("no error setting -handicap %s", flag)
+    }
+  }
+}
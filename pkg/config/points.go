@@ -0,0 +1,44 @@
+package config
+
+import (
+  "fmt"
+  "strings"
+  "gopkg.in/yaml.v2"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+  "github.com/taemon1337/arena-nerf/pkg/common"
+)
+
+// PointsConfig sets what a hit on a node, or on a single sensor of a node, is worth
+type PointsConfig struct {
+  Points        map[string]int    `yaml:"points" json:"points"` // keyed by <node> or <node>:<sensor-id>
+}
+
+func NewPointsConfig() *PointsConfig {
+  return &PointsConfig{
+    Points:       map[string]int{},
+  }
+}
+
+// Set parses a -points flag in the form <node>[:<sensor-id>]=<points>
+func (pc *PointsConfig) Set(value string) error {
+  key, val, ok := strings.Cut(value, "=")
+  if !ok || key == "" {
+    return constants.ERR_INVALID_POINTS_FLAG
+  }
+
+  points, err := common.ParseInt(val)
+  if err != nil {
+    return err
+  }
+
+  pc.Points[key] = points
+  return nil
+}
+
+func (pc *PointsConfig) String() string {
+  yamlBytes, err := yaml.Marshal(pc)
+  if err != nil {
+    return fmt.Sprintf("Error marshalling points config into yaml: %s", err)
+  }
+  return string(yamlBytes)
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+  "os"
   "fmt"
   "log"
   "strings"
@@ -21,6 +22,7 @@ type SensorConfig struct {
 
 type SensorsConfig struct {
   Configs       map[string]*SensorConfig    `yaml:"configs" json:"configs"`
+  Updates       []string                    `yaml:"updates" json:"updates"` // changes pushed from the controller (see Update)
 }
 
 func NewSensorConfig(id, device, chip, hitpin, ledpin string, ledcount, debouncetime int) *SensorConfig {
@@ -50,6 +52,7 @@ func DefaultSensorConfig(id string) *SensorConfig {
 func NewSensorsConfig() *SensorsConfig {
   return &SensorsConfig{
     Configs:    map[string]*SensorConfig{},
+    Updates:    []string{},
   }
 }
 
@@ -118,3 +121,122 @@ func (sc *SensorsConfig) String() string {
   }
   return string(yamlBytes)
 }
+
+// Update applies a sensor change pushed from the controller, returning the id of the sensor it changed, in the form of
+// add:<id>:<device>:<gpiochip>:<hitpin>:<ledpin>[:<ledcount>], remove:<id>, debounce:<id>:<ms> or ledcount:<id>:<count>
+func (sc *SensorsConfig) Update(update string) (string, error) {
+  action, rest, ok := strings.Cut(update, constants.SPLIT)
+  if !ok || rest == "" {
+    return "", constants.ERR_INVALID_SENSOR_UPDATE
+  }
+
+  if action == constants.SENSOR_UPDATE_ADD {
+    added := NewSensorsConfig()
+    if err := added.Set(rest); err != nil {
+      return "", err
+    }
+
+    id, _, _ := strings.Cut(rest, constants.SPLIT)
+    cfg := added.Configs[id]
+    if err := cfg.Error(); err != nil && err != constants.ERR_TEST_SENSOR {
+      return "", err
+    }
+
+    sc.Configs[id] = cfg
+    sc.record(action, id, update)
+    return id, nil
+  }
+
+  id, value, _ := strings.Cut(rest, constants.SPLIT)
+  current, ok := sc.Configs[id]
+  if !ok {
+    return "", constants.ERR_NO_SENSOR_BY_NAME
+  }
+
+  // change a copy, the running sensor still holds the old config until it is restarted
+  cfg := *current
+  switch action {
+    case constants.SENSOR_UPDATE_REMOVE:
+      delete(sc.Configs, id)
+      sc.record(action, id, update)
+      return id, nil
+    case constants.SENSOR_UPDATE_DEBOUNCE:
+      debounce, err := common.ParseInt(value)
+      if err != nil || debounce < 0 {
+        return "", constants.ERR_INVALID_SENSOR_UPDATE
+      }
+      cfg.Debounce = debounce
+    case constants.SENSOR_UPDATE_LEDCOUNT:
+      count, err := common.ParseInt(value)
+      if err != nil || count < 1 {
+        return "", constants.ERR_INVALID_LED_COUNT
+      }
+      cfg.Ledcount = count
+    default:
+      return "", constants.ERR_INVALID_SENSOR_UPDATE
+  }
+
+  sc.Configs[id] = &cfg
+  sc.record(action, id, update)
+  return id, nil
+}
+
+// record keeps the update, dropping the earlier ones it makes pointless so the list only grows with the sensors
+func (sc *SensorsConfig) record(action, id, update string) {
+  updates := []string{}
+  for _, earlier := range sc.Updates {
+    eaction, rest, _ := strings.Cut(earlier, constants.SPLIT)
+    eid, _, _ := strings.Cut(rest, constants.SPLIT)
+    if eid == id && (eaction == action || action == constants.SENSOR_UPDATE_ADD || action == constants.SENSOR_UPDATE_REMOVE) {
+      continue
+    }
+    updates = append(updates, earlier)
+  }
+  sc.Updates = append(updates, update)
+}
+
+// LoadSensors applies the sensor changes saved from the controller on top of the -sensor flags,
+// a change that no longer fits the flags (i.e. the sensor is gone) is logged and skipped
+func (c *Config) LoadSensors() error {
+  if c.SensorsFile == "" || !common.FileExist(c.SensorsFile) {
+    return nil
+  }
+
+  data, err := os.ReadFile(c.SensorsFile)
+  if err != nil {
+    return err
+  }
+
+  saved := NewSensorsConfig()
+  if err := yaml.Unmarshal(data, saved); err != nil {
+    return err
+  }
+
+  for _, update := range saved.Updates {
+    if _, err := c.SensorsConf.Update(update); err != nil {
+      c.Printf("skipping saved sensor change %s: %s", update, err)
+      continue
+    }
+    c.Printf("applied saved sensor change %s from %s", update, c.SensorsFile)
+  }
+  return nil
+}
+
+// SaveSensors keeps the sensor changes pushed from the controller for the next start of the node
+func (c *Config) SaveSensors() error {
+  if c.SensorsFile == "" {
+    return nil
+  }
+
+  data, err := yaml.Marshal(&SensorsConfig{Configs: map[string]*SensorConfig{}, Updates: c.SensorsConf.Updates})
+  if err != nil {
+    return err
+  }
+
+  // write then rename, so dying mid-write never leaves a broken sensors file
+  tmp := c.SensorsFile + ".tmp"
+  if err := os.WriteFile(tmp, data, 0640); err != nil {
+    return err
+  }
+  return os.Rename(tmp, c.SensorsFile)
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+  "log"
+  "slices"
+  "testing"
+  "path/filepath"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+func newTestSensorsConfig(t *testing.T) *SensorsConfig {
+  sc := NewSensorsConfig()
+  for _, flag := range []string{"one:/dev/gpiochip0:gpiochip0:17:18", "two:/dev/gpiochip0:gpiochip0:22:23:8"} {
+    if err := sc.Set(flag); err != nil {
+      t.Fatalf("cannot set -sensor %s: %s", flag, err)
+    }
+  }
+  return sc
+}
+
+func TestSensorsConfigUpdate(t *testing.T) {
+  tests := []struct {
+    name    string
+    update  string
+    id      string
+    err     error
+    check   func(sc *SensorsConfig) bool
+  }{
+    {"debounce", "debounce:one:250", "one", nil, func(sc *SensorsConfig) bool { return sc.Configs["one"].Debounce == 250 }},
+    {"led count", "ledcount:two:12", "two", nil, func(sc *SensorsConfig) bool { return sc.Configs["two"].Ledcount == 12 }},
+    {"remove", "remove:one", "one", nil, func(sc *SensorsConfig) bool { return sc.Configs["one"] == nil }},
+    {"add", "add:three:/dev/gpiochip1:gpiochip1:5:6", "three", nil, func(sc *SensorsConfig) bool { return sc.Configs["three"].Hitpin == "5" }},
+    {"add a test sensor", "add:test1", "test1", nil, func(sc *SensorsConfig) bool { return sc.Configs["test1"] != nil }},
+    {"add without pins", "add:four:/dev/gpiochip1", "", constants.ERR_INVALID_SENSOR_FLAG, nil},
+    {"unknown sensor", "debounce:five:250", "", constants.ERR_NO_SENSOR_BY_NAME, nil},
+    {"negative debounce", "debounce:one:-1", "", constants.ERR_INVALID_SENSOR_UPDATE, nil},
+    {"no leds", "ledcount:one:0", "", constants.ERR_INVALID_LED_COUNT, nil},
+    {"unknown action", "rename:one:uno", "", constants.ERR_INVALID_SENSOR_UPDATE, nil},
+    {"no sensor", "remove", "", constants.ERR_INVALID_SENSOR_UPDATE, nil},
+  }
+
+  for _, tt := range tests {
+    sc := newTestSensorsConfig(t)
+    before := *sc.Configs["one"]
+
+    id, err := sc.Update(tt.update)
+    if err != tt.err || id != tt.id {
+      t.Errorf("%s: updated %q with error %v, want %q with %v", tt.name, id, err, tt.id, tt.err)
+      continue
+    }
+    if err != nil {
+      if len(sc.Updates) != 0 || *sc.Configs["one"] != before {
+        t.Errorf("%s: failed update changed the config", tt.name)
+      }
+      continue
+    }
+    if !tt.check(sc) {
+      t.Errorf("%s: update not applied", tt.name)
+    }
+    if !slices.Equal(sc.Updates, []string{tt.update}) {
+      t.Errorf("%s: recorded %v", tt.name, sc.Updates)
+    }
+  }
+}
+
+func TestSensorsConfigRecord(t *testing.T) {
+  sc := newTestSensorsConfig(t)
+  for _, update := range []string{"debounce:one:250", "ledcount:two:12", "debounce:one:300", "add:three:/dev/gpiochip1:gpiochip1:5:6", "debounce:three:50", "remove:three"} {
+    if _, err := sc.Update(update); err != nil {
+      t.Fatalf("cannot update %s: %s", update, err)
+    }
+  }
+
+  // the last debounce of one wins, and removing three drops everything it had
+  want := []string{"ledcount:two:12", "debounce:one:300", "remove:three"}
+  if !slices.Equal(sc.Updates, want) {
+    t.Fatalf("recorded %v, want %v", sc.Updates, want)
+  }
+}
+
+func TestSaveLoadSensors(t *testing.T) {
+  file := filepath.Join(t.TempDir(), "sensors.yaml")
+
+  cfg := NewConfig(log.Default())
+  cfg.SensorsFile = file
+  cfg.SensorsConf = newTestSensorsConfig(t)
+  for _, update := range []string{"debounce:one:250", "add:three:/dev/gpiochip1:gpiochip1:5:6", "ledcount:two:12"} {
+    if _, err := cfg.SensorsConf.Update(update); err != nil {
+      t.Fatalf("cannot update %s: %s", update, err)
+    }
+  }
+  if err := cfg.SaveSensors(); err != nil {
+    t.Fatalf("cannot save sensors: %s", err)
+  }
+
+  // the node restarts with the same flags, less sensor two
+  restarted := NewConfig(log.Default())
+  restarted.SensorsFile = file
+  restarted.SensorsConf = NewSensorsConfig()
+  if err := restarted.SensorsConf.Set("one:/dev/gpiochip0:gpiochip0:17:18"); err != nil {
+    t.Fatalf("cannot set -sensor: %s", err)
+  }
+  if err := restarted.LoadSensors(); err != nil {
+    t.Fatalf("cannot load sensors: %s", err)
+  }
+
+  sc := restarted.SensorsConf
+  if sc.Configs["one"].Debounce != 250 || sc.Configs["three"] == nil || sc.Configs["two"] != nil {
+    t.Fatalf("loaded sensors %v, want one with the saved debounce and three added", sc.Configs)
+  }
+  if sc.Configs["one"].Hitpin != "17" {
+    t.Fatalf("saved change replaced the -sensor flag pins of one")
+  }
+
+  // nothing saved yet is not an error
+  fresh := NewConfig(log.Default())
+  fresh.SensorsFile = filepath.Join(t.TempDir(), "missing.yaml")
+  if err := fresh.LoadSensors(); err != nil {
+    t.Fatalf("cannot start without a sensors file: %s", err)
+  }
+}
@@ -4,6 +4,7 @@ import (
   "log"
   "time"
   "context"
+  "strconv"
 
   "github.com/hashicorp/serf/serf"
   "github.com/hashicorp/serf/cmd/serf/command/agent"
@@ -102,6 +103,19 @@ func (c *Connector) RegisterEventHandler(eh agent.EventHandler) {
   c.agent.RegisterEventHandler(eh)
 }
 
+// LamportTime is serf's lamport clock for user events, 0 when not connected
+func (c *Connector) LamportTime() uint64 {
+  if !c.IsConnected() {
+    return 0
+  }
+
+  ltime, err := strconv.ParseUint(c.agent.Serf().Stats()["event_time"], 10, 64)
+  if err != nil {
+    return 0
+  }
+  return ltime
+}
+
 func (c *Connector) Serf() *serf.Serf {
   return c.agent.Serf()
 }
@@ -1,5 +1,19 @@
 package constants
 
+import (
+  "time"
+)
+
 var (
   CHANNEL_WIDTH = 5
+  GAME_CHAN_TIMEOUT = 5 * time.Second // how long the engine waits on a busy game before dropping an event
+  REPORT_RETRIES = 3 // times a node sends a hit report before leaving it to the journal replay
+
+  // names of the channels events are dropped from when full, reported by node:health
+  DROPPED_GPIO_CHAN = "gpio"
+  DROPPED_HIT_CHAN = "hit"
+  DROPPED_GAME_CHAN = "game"
+  DROPPED_SENSOR_CHAN = "sensor"
+  DROPPED_REQUEST_CHAN = "request"
+  DROPPED_SELFTEST_CHAN = "selftest"
 )
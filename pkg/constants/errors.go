@@ -6,6 +6,8 @@ import (
 
 var (
   ERR_GAME_RUNNING = errors.New("current game is still running")
+  ERR_NO_GAME_RUNNING = errors.New("there is no game running")
+  ERR_GAME_NOT_PAUSED = errors.New("the game is not paused")
   ERR_NODES_NOT_READY = errors.New("current game nodes are not ready (or not enough are ready")
   ERR_MIN_NODE_COUNT = errors.New("not enough nodes")
   ERR_MAX_NODE_COUNT = errors.New("too many nodes")
@@ -13,8 +15,38 @@ var (
   ERR_MAX_TEAM_COUNT = errors.New("too many teams")
   ERR_REQUIRED_NODE = errors.New("missing required node")
   ERR_REQUIRED_TEAM = errors.New("missing required team")
+  ERR_INVALID_TARGET_HIT = errors.New("invalid target hit payload - must be <node>:<sensor-id>:<sensor-color>:<reaction-ms>")
+  ERR_INVALID_TARGET_DAMAGE = errors.New("invalid target damage payload - must be <node>:<sensor-id>:<sensor-color>")
+  ERR_INVALID_TARGET_CAPTURE = errors.New("invalid target capture payload - must be <node>:<sensor-id>:<team>")
+  ERR_INVALID_TARGET_MISS = errors.New("invalid target miss payload - must be <node>:<sensor-id>")
+  ERR_INVALID_HIT_REPORT = errors.New("invalid hit report payload - must be <node>:<game-id>:<run>:<seq>:<sensor-id>:<team>:<hits>:<points>")
+  ERR_INVALID_HIT_RECORDS_QUERY = errors.New("invalid hit records query - must be <game-id>:<offset>")
+  ERR_INVALID_SELFTEST_RESULT = errors.New("invalid self test result - must be <node>:<sensor-id>=<pass|fail>,...")
+  ERR_NO_NODE = errors.New("no node given")
+  ERR_REQUEST_CHAN_FULL = errors.New("game engine request chan is full")
+  ERR_GAME_CHAN_FULL = errors.New("game chan is full")
   ERR_INVALID_NODE_HIT = errors.New("invalid node hit payload - must be <sensor-id>:<sensor-color>:<hit-count>")
   ERR_API_ACTIONS_NOT_ALLOWED = errors.New("api actions not allowed")
   ERR_ONGOING_GAME = errors.New("there is an active game")
   ERR_UI_ACTION_NOT_ALLOWED = errors.New("that UI action is not supported or allowed")
+  ERR_UNSUPPORTED_GAME_MODE = errors.New("unsupported game mode")
+  ERR_NO_GAME_RULES_NAME = errors.New("game rules must have a name")
+  ERR_BUILT_IN_GAME_MODE = errors.New("game rules cannot be named after a built in game mode")
+  ERR_DUPLICATE_GAME_MODE = errors.New("game mode already loaded -")
+  ERR_INVALID_LIGHTING = errors.New("invalid lighting - must be none, random, all, target or hill")
+  ERR_INVALID_END_CONDITION = errors.New("invalid end condition - must be time or score")
+  ERR_INVALID_TIE_BREAK = errors.New("invalid tie break - must be draw, overtime or sudden-death")
+  ERR_INVALID_GAME_RESET = errors.New("invalid game reset payload - must be <game-id>:<seed>[:resume]")
+  ERR_INVALID_SET_TARGET = errors.New("invalid set target payload - must be <node>:<sensor-id>:<color>")
+  ERR_NO_COURSE = errors.New("no time trial course set - use -course <node>:<sensor-id>,...")
+  ERR_INVALID_COURSE = errors.New("invalid time trial course - every stop must be <node>:<sensor-id> on a game node")
+  ERR_INVALID_RECOVERY = errors.New("invalid recovery - must be ask, resume or abort")
+  ERR_NO_CHECKPOINT = errors.New("no unfinished game to recover")
+  ERR_QUEUED_GAME_NOT_FOUND = errors.New("no such game in the queue")
+  ERR_INVALID_ARENA_FLAG = errors.New("invalid arena - must be <arena>=<node>,<node>")
+  ERR_UNKNOWN_ARENA = errors.New("no such arena")
+  ERR_INVALID_POINTS_FLAG = errors.New("invalid points - must be <node>[:<sensor-id>]=<points>")
+  ERR_INVALID_GAME_OVERRIDE = errors.New("invalid game override - counts cannot be negative and minimums cannot be over maximums")
+  ERR_INVALID_QUEUE_POSITION = errors.New("invalid queue position")
+  ERR_INVALID_HANDICAP_FLAG = errors.New("invalid -handicap flag; expects <team>:<bonus>[:<multiplier>[:<winning-score>]] with a multiplier > 0")
 )
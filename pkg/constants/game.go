@@ -1,27 +1,55 @@
 package constants
 
+import (
+  "time"
+)
+
 var (
   // game modes
   GAME_MODE = "game:mode" // set game mode
   GAME_MODE_SIMULATION = "simulation"
+  GAME_MODE_KING_OF_THE_HILL = "kingofthehill"
+  GAME_MODE_WHACK_A_MOLE = "whackamole"
+  GAME_MODE_ELIMINATION = "elimination"
+  GAME_MODE_TERRITORY = "territory"
+  GAME_MODE_DRILL = "drill"
+  GAME_MODE_TIME_TRIAL = "timetrial"
 
   // game actions
   GAME_ACTION_BEGIN = "game:begin"
   GAME_ACTION_END = "game:end"
   GAME_ACTION_PAUSE = "game:pause"
+  GAME_ACTION_RESUME = "game:resume"
+  GAME_ACTION_COUNTDOWN = "game:countdown"    // payload is the agreed start time in unix milliseconds
   GAME_ACTION_RESET = "game:reset"
   GAME_ACTION_OFF = "game:off"
 
   // game states
   GAME_STATUS_INIT = "game:init"
+  GAME_STATUS_STARTING = "game:starting"     // counting down to the agreed start time
   GAME_STATUS_RUNNING = "game:running"
+  GAME_STATUS_PAUSED = "game:paused"
   GAME_STATUS_ENDED = "game:over"
   GAME_STATUS_FAILED = "game:failed"
+  GAME_STATUS_ABORTED = "game:aborted" // the controller died mid-game and the game was not resumed
+  GAME_RESUME_ID = "resume"           // a game reset that resumes a checkpointed game, keeping any hits
   GAME_TEAMS = "game:teams"
   GAME_WINNER = "game:winner"
   GAME_ERROR = "game:error"
+  GAME_RULES = "game:rules" // scoring and lighting rules sent to each node
+  GAME_HILL = "game:hill" // announces the current hill node
+  GAME_PHASE = "game:phase" // announces regulation, overtime, sudden death or a draw
+
+  // game phases
+  GAME_PHASE_REGULATION = "regulation"
+  GAME_PHASE_OVERTIME = "overtime"           // a timed period played when regulation ends in a tie
+  GAME_PHASE_SUDDEN_DEATH = "sudden-death"   // the next tied team to score wins
+  GAME_PHASE_DRAW = "draw"                   // the game ended level and every tied team shares the win
 
   NODE_SCOREBOARD = "node:scoreboard"
+  NODE_SENSORS = "node:sensors" // lists the sensor ids on each node
+  NODE_HEALTH = "node:health" // uptime, version and sensor hardware of each node
+  NODE_HITS = "node:hits" // pages through a node's hit records of a game, <game-id>:<offset>
 
   // game event names
   TARGET_HIT = "target:hit"
@@ -29,11 +57,29 @@ var (
   SENSOR_HIT = "sensor:hit"
   SENSOR_HIT_REQUEST = "request:sensor:hit"
   SENSOR_COLOR_REQUEST = "request:sensor:color"
+  TARGET_REQUEST = "request:target"           // lights a single target sensor on a node
+  TARGET_CLEAR = "target:clear"               // turns off the current target on a node
+  TARGET_DAMAGE = "target:damage"             // node reports a hit on a target with health
+  TARGET_DOWN = "target:down"                 // a target ran out of health and is eliminated
+  TARGET_CAPTURE = "target:capture"           // node reports a sensor switched to another team
+  TARGET_MISS = "target:miss"                 // node reports a hit on a sensor that was not the target
+  FALSE_START = "false:start"                 // node reports a hit during the countdown
+  TEAM_STREAK = "team:streak"                 // a team earned a streak bonus
+  HITS_PREFIX = "hits:"                       // prefixes a team in the node hits to count its raw hits, whatever they were worth
+  HIT_REPORT = "hit:report"                   // node pushes every hit it records to the controller as it happens
+  SCORE_DRIFT = "score:drift"                 // the pushed scores did not match the polled node scoreboards
 
   // game event requests (from game to game engine)
   RANDOM_TEAM_HIT = "rand:team:hit"           // game requests engine for a random team target hit count
   RANDOM_SENSOR_HIT = "rand:sensor:hit"       // game requests engine for a random sensor hit (count=1)
   RANDOM_SENSOR_COLOR = "rand:sensor:color"   // game requests engine for a random sensor color to be set
+  RANDOM_HILL = "rand:hill"                   // game requests engine to move the hill to a random node
+  RANDOM_TARGET = "rand:target"               // game requests engine to light a new random target
+  RANDOM_SENSOR_COLORS = "rand:sensor:colors" // game requests engine for a random color on every sensor
+  TERRITORY_TICK = "territory:tick"           // game requests engine to award points for held sensors
+  SET_TARGET = "set:target"                   // game requests engine to light a specific <node>:<sensor-id>:<color> target
+  TRIAL_START = "trial:start"                 // game requests engine to start the clock on a team's time trial
+  TRIAL_FINISH = "trial:finish"               // game requests engine to stop the clock on a team's time trial
 
   // node
   NODE_READY = "node:ready"
@@ -45,4 +91,32 @@ var (
   RED_TEAM = "red"
   YELLOW_TEAM = "yellow"
   GREEN_TEAM = "green"
+
+  // target lighting behaviors for game modes declared in yaml
+  LIGHTING_NONE = "none"                      // sensors are never lit by the game
+  LIGHTING_RANDOM = "random"                  // one random sensor changes color each interval
+  LIGHTING_ALL = "all"                        // every sensor changes color each interval
+  LIGHTING_TARGET = "target"                  // a single target is lit until hit or the interval passes
+  LIGHTING_HILL = "hill"                      // only the hill node is lit, it moves each interval
+
+  // end conditions for game modes declared in yaml
+  END_ON_TIME = "time"
+  END_ON_SCORE = "score"
+
+  // tie break policies when a game ends level
+  TIE_BREAK_DRAW = GAME_PHASE_DRAW
+  TIE_BREAK_OVERTIME = GAME_PHASE_OVERTIME
+  TIE_BREAK_SUDDEN_DEATH = GAME_PHASE_SUDDEN_DEATH
+
+  // game defaults
+  DEFAULT_HILL_INTERVAL = 1 * time.Minute
+  DEFAULT_TARGET_TIMEOUT = 10 * time.Second
+  DEFAULT_LIGHT_INTERVAL = 3 * time.Second
+  DEFAULT_OVERTIME = 1 * time.Minute
+
+  // what to do with a game left unfinished when the controller died
+  RECOVERY_ASK = "ask"                        // wait for ui:recovery:resume or ui:recovery:abort
+  RECOVERY_RESUME = "resume"
+  RECOVERY_ABORT = "abort"
+  CHECKPOINT_FILE = "current.checkpoint"      // kept in -logdir, not .json so it is not listed as a game log
 )
@@ -10,4 +10,5 @@ var (
   SENSOR_COLOR = "sensor:color"
   COLOR_YELLOW = "yellow"
   BLINK_DELAY = 100 * time.Millisecond
+  PAUSED_BLINK_INTERVAL = 1 * time.Second
 )
@@ -5,5 +5,16 @@ var (
   TAG_CTRL = "ctrl"
   TAG_TRUE = "true"
   TAG_FALSE = "false"
+  TAG_ARENA = "arena"
+  ARENA_SPLIT = "/"   // prefixes event and query names with the arena they are for, i.e. north/game:begin
   NODE_TAGS = map[string]string{TAG_NODE: TAG_TRUE}
+  CTRL_TAGS = map[string]string{TAG_CTRL: TAG_TRUE}
+  QUERY_ACK = "ack"
+  QUERY_RESPONSE_HEADROOM = 256       // left out of serf's query response size limit for the message around the payload
+  NODE_SELFTEST = "node:selftest"     // blinks each sensor in turn and waits for a test hit on it, payload is how long to wait
+  NODE_IDENTIFY = "node:identify"     // flashes a node's sensors so it can be found in the field
+  SELFTEST_RESULT = "selftest:result" // <node>:<sensor-id>=<pass|fail>,...
+  SELFTEST_PASS = "pass"
+  SELFTEST_FAIL = "fail"
+  IDENTIFY_FLASHES = "10"
 )
@@ -9,6 +9,18 @@ var (
   TEST_SENSOR_PREFIX = "test"
   RANDOM_SENSOR_ID = "rand"
   RANDOM_COLOR_ID = "rand"
+  ALL_SENSOR_ID = "all"
+  NONE_COLOR_ID = "none"
+  SENSOR_ELIMINATED = "sensor:eliminated"
+  SENSOR_FLASH = "sensor:flash"
+  SENSOR_PAUSED = "sensor:paused"
+  SENSOR_RESUMED = "sensor:resumed"
+  SENSOR_COUNTDOWN = "sensor:countdown"
+  SENSOR_CONFIG = "sensor:config" // changes a sensor of a node at runtime, see SensorsConfig.Update
+  SENSOR_UPDATE_ADD = "add"
+  SENSOR_UPDATE_REMOVE = "remove"
+  SENSOR_UPDATE_DEBOUNCE = "debounce"
+  SENSOR_UPDATE_LEDCOUNT = "ledcount"
   NONE_SENSOR_ID = "none"
   ERR_SENSORS_DISABLED = errors.New("sensors are disabled")
   ERR_NO_SENSORS = errors.New("no sensors setup")
@@ -21,6 +33,7 @@ var (
   ERR_INVALID_SENSOR_NUMBER = errors.New("invalid -sensor <number>, must be 1-4")
   ERR_INVALID_LED_COUNT = errors.New("invalid LED count, must be > 1")
   ERR_TEST_SENSOR = errors.New("sensor is a test only sensor")
+  ERR_INVALID_SENSOR_UPDATE = errors.New("invalid sensor update; expects add:<id>:<device>:<gpiochip>:<hit-pin>:<led-pin>[:<led-count>], remove:<id>, debounce:<id>:<ms> or ledcount:<id>:<count>")
   ERR_SENSOR_HIT_STOPPED = errors.New("a sensor hit input has stopped")
 )
 
@@ -11,4 +11,6 @@ var (
   JOIN_REPLAY = false
   ERR_EXISTING_CONNECTION = errors.New("already connected")
   ERR_NO_AGENT_CONFIG = errors.New("no node agent config")
+  ERR_CONNECTOR_DISABLED = errors.New("connector is disabled")
+  ERR_NO_CONTROLLER_ACK = errors.New("no controller acknowledged the query")
 )
@@ -3,4 +3,5 @@ package constants
 var (
   SPLIT = ":"
   COMMA = ","
+  VERSION = "2.0.1" // set at build time with -ldflags "-X github.com/taemon1337/arena-nerf/pkg/constants.VERSION=<version>"
 )
@@ -0,0 +1,95 @@
+package controller
+
+import (
+  "os"
+  "log"
+  "fmt"
+  "context"
+
+  "github.com/hashicorp/serf/serf"
+
+  "github.com/taemon1337/arena-nerf/pkg/common"
+  "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/connector"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+  "github.com/taemon1337/arena-nerf/pkg/game"
+)
+
+// Arena is a field of nodes (selected by their arena tag) playing its own games with its own engine
+type Arena struct {
+  Name          string
+  conf          *config.Config
+  engine        *game.GameEngine
+  gamechan      *game.GameChannel
+  *log.Logger
+}
+
+func NewArena(cfg *config.Config, gamechan *game.GameChannel, logger *log.Logger) *Arena {
+  if cfg.Arena != "" {
+    logger = log.New(logger.Writer(), fmt.Sprintf("[CTRL %s]: ", cfg.Arena), logger.Flags())
+  }
+
+  if cfg.Arena != "" && cfg.Logdir != "" {
+    if err := os.MkdirAll(cfg.Logdir, os.ModePerm); err != nil {
+      logger.Printf("error creating arena log directory %s: %s", cfg.Logdir, err)
+    }
+  }
+
+  return &Arena{
+    Name:     cfg.Arena,
+    conf:     cfg,
+    engine:   game.NewGameEngine(cfg, gamechan, logger),
+    gamechan: gamechan,
+    Logger:   logger,
+  }
+}
+
+// SendEventToEngine hands an event to the arena's engine without blocking, returning an error when it was dropped
+func (a *Arena) SendEventToEngine(e game.GameEvent) error {
+  select {
+    case a.gamechan.RequestChan <- e:
+      return nil
+    default:
+      a.Printf("request chan is full - discarding event: %s", e)
+      a.engine.Dropped.Add(constants.DROPPED_REQUEST_CHAN)
+      return constants.ERR_REQUEST_CHAN_FULL
+  }
+}
+
+// ListenToGame sends the engine's events and queries out to the arena's nodes
+func (a *Arena) ListenToGame(ctx context.Context, conn *connector.Connector) error {
+  for {
+    select {
+    case <-ctx.Done():
+      return ctx.Err()
+    case e := <-a.gamechan.NodeChan:
+      switch e.Event {
+        default:
+          // every node gets every event, the arena prefix lets the nodes of other arenas skip it
+          a.Printf("sending event out to all nodes: %s", e.Event)
+          if err := conn.UserEvent(common.ArenaEvent(a.Name, e.Event), e.Payload, a.conf.Coalesce); err != nil {
+            a.Printf("error sending %s event: %s", e.Event, err)
+          }
+      }
+    case q := <-a.gamechan.QueryChan:
+      a.Printf("controller received game query: %s", q)
+      switch q.Query {
+        default:
+          // by default send all queries from game engine to all nodes (of the arena, by tag)
+          data := map[string][]byte{}
+          resp, err := conn.Query(q.Query, q.Payload, &serf.QueryParam{FilterNodes: q.Nodes, FilterTags: q.Tags})
+          if err != nil {
+            q.Response <- game.NewGameQueryResponse(data, err)
+          }
+          for r := range resp.ResponseCh() {
+            data[r.From] = r.Payload
+            if len(q.Nodes) > 0 && len(data) == len(q.Nodes) {
+              resp.Close() // everyone asked has answered, no need to wait out the timeout
+              break
+            }
+          }
+          q.Response <- game.NewGameQueryResponse(data, err)
+      }
+    }
+  }
+}
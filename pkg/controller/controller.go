@@ -8,7 +8,9 @@ import (
   "golang.org/x/sync/errgroup"
   "github.com/hashicorp/serf/serf"
 
+  "github.com/taemon1337/arena-nerf/pkg/common"
   "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
   "github.com/taemon1337/arena-nerf/pkg/connector"
   "github.com/taemon1337/arena-nerf/pkg/game"
   "github.com/taemon1337/arena-nerf/pkg/server"
@@ -16,9 +18,9 @@ import (
 
 type Controller struct {
   conf          *config.Config
-  engine        *game.GameEngine
+  arenas        map[string]*Arena
+  names         []string        // the arenas in order, the first is the default arena
   server        *server.Server
-  gamechan      *game.GameChannel
   conn          *connector.Connector
   *log.Logger
 }
@@ -26,11 +28,36 @@ type Controller struct {
 func NewController(cfg *config.Config, gamechan *game.GameChannel, logger *log.Logger) *Controller {
   logger = log.New(logger.Writer(), "[CTRL]: ", logger.Flags())
 
+  if cfg.ModesDir != "" {
+    modes, err := game.LoadGameRules(cfg.ModesDir)
+    if err != nil {
+      logger.Printf("skipped game modes in %s: %s", cfg.ModesDir, err)
+    }
+    for _, rules := range modes {
+      logger.Printf("loaded game mode %s", rules.Name)
+      game.RegisterGameRules(rules)
+    }
+  }
+
+  names := cfg.ArenasConf.Names()
+  if len(names) == 0 {
+    names = []string{""} // a single arena of every node
+  }
+
+  arenas := map[string]*Arena{}
+  for i, name := range names {
+    ch := gamechan
+    if i > 0 {
+      ch = game.NewGameChannel()
+    }
+    arenas[name] = NewArena(cfg.ForArena(name), ch, logger)
+  }
+
   return &Controller{
     conf:     cfg,
-    engine:   game.NewGameEngine(cfg, gamechan, logger),
+    arenas:   arenas,
+    names:    names,
     server:   nil,
-    gamechan: gamechan,
     conn:     connector.NewConnector(cfg, logger),
     Logger:   logger,
   }
@@ -61,15 +88,33 @@ func (ctrl *Controller) Start(ctx context.Context) error {
       return ctrl.conn.Join(ctx)
     })
 
-    g.Go(func () error {
-      return ctrl.ListenToGame(ctx)
-    })
+    for _, arena := range ctrl.arenas {
+      arena := arena
+      g.Go(func () error {
+        return arena.ListenToGame(ctx, ctrl.conn)
+      })
+    }
   }
 
   if ctrl.conf.EnableGameEngine {
-    g.Go(func() error {
-      return ctrl.engine.Start(ctx)
-    })
+    for _, arena := range ctrl.arenas {
+      arena := arena
+      g.Go(func() error {
+        return arena.engine.Start(ctx)
+      })
+
+      g.Go(func() error {
+        return arena.engine.RunQueue(ctx)
+      })
+
+      g.Go(func() error {
+        interval, err := time.ParseDuration(ctrl.conf.HealthInterval)
+        if err != nil {
+          return err
+        }
+        return arena.engine.MonitorHealth(ctx, interval)
+      })
+    }
   } else {
     ctrl.Printf("game engine disabled")
   }
@@ -88,12 +133,59 @@ func (ctrl *Controller) Start(ctx context.Context) error {
   return g.Wait()
 }
 
-func (ctrl *Controller) NewGame(mode string) error {
-  return ctrl.engine.NewGame(mode)
+// Arenas lists the arenas by name, which is a single unnamed arena unless -arena is set
+func (ctrl *Controller) Arenas() []string {
+  return ctrl.names
+}
+
+// Arena looks up an arena by name, no name is the default arena
+func (ctrl *Controller) Arena(name string) (*Arena, error) {
+  if name == "" {
+    name = ctrl.names[0]
+  }
+
+  if arena, ok := ctrl.arenas[name]; ok {
+    return arena, nil
+  }
+  return nil, constants.ERR_UNKNOWN_ARENA
+}
+
+func (ctrl *Controller) NewGame(arena, mode string) error {
+  a, err := ctrl.Arena(arena)
+  if err != nil {
+    return err
+  }
+  return a.engine.NewGame(mode, nil)
+}
+
+func (ctrl *Controller) StartGame(ctx context.Context, arena string) error {
+  a, err := ctrl.Arena(arena)
+  if err != nil {
+    return err
+  }
+  return a.engine.StartGame(ctx)
 }
 
-func (ctrl *Controller) StartGame(ctx context.Context) error {
-  return ctrl.engine.StartGame(ctx)
+// RecoverGame returns true if a game left unfinished by a controller restart was resumed and played out
+func (ctrl *Controller) RecoverGame(ctx context.Context, arena string) (bool, error) {
+  a, err := ctrl.Arena(arena)
+  if err != nil {
+    return false, err
+  }
+  return a.engine.RecoverGame(ctx)
+}
+
+func (ctrl *Controller) StartMatch(ctx context.Context, arena, mode string) error {
+  a, err := ctrl.Arena(arena)
+  if err != nil {
+    return err
+  }
+
+  roundbreak, err := time.ParseDuration(ctrl.conf.RoundBreak)
+  if err != nil {
+    return err
+  }
+  return a.engine.StartMatch(ctx, mode, ctrl.conf.Rounds, roundbreak)
 }
 
 func (ctrl *Controller) HandleEvent(e serf.Event) {
@@ -101,38 +193,48 @@ func (ctrl *Controller) HandleEvent(e serf.Event) {
     log.Printf("EVENT: %s", e)
   }
   if e.EventType() == serf.EventQuery {
-    log.Printf("QUERY: %s", e)
+    q := e.(*serf.Query)
+    name, query := common.ParseArenaEvent(q.Name)
+    switch query {
+      case constants.TARGET_HIT, constants.TARGET_MISS, constants.TARGET_DAMAGE, constants.TARGET_CAPTURE, constants.HIT_REPORT, constants.FALSE_START, constants.SELFTEST_RESULT:
+        // nodes report target hits directly, hand them over to the game engine of their arena
+        arena, ok := ctrl.arenas[name]
+        if !ok {
+          ctrl.Printf("ignoring query %s from unknown arena '%s'", query, name)
+          return
+        }
+
+        // no ack for a dropped event, so the node sends it again
+        evt := game.NewGameEvent(query, q.Payload)
+        if query == constants.HIT_REPORT {
+          evt.Reply = make(chan error, 1) // nor for a hit the engine did not count
+        }
+        if err := arena.SendEventToEngine(evt); err != nil {
+          return
+        }
+        go ctrl.AckQuery(q, evt.Reply)
+      default:
+        log.Printf("QUERY: %s", e)
+    }
   }
 }
 
-func (ctrl *Controller) ListenToGame(ctx context.Context) error {
-  for {
+// AckQuery acknowledges a node's query, once the engine answers if it was asked to (see GameEvent.Reply)
+func (ctrl *Controller) AckQuery(q *serf.Query, reply chan error) {
+  if reply != nil {
     select {
-    case <-ctx.Done():
-      return ctx.Err()
-    case e := <-ctrl.gamechan.NodeChan:
-      switch e.Event {
-        default:
-          ctrl.Printf("sending event out to all nodes: %s", e.Event)
-          if err := ctrl.conn.UserEvent(e.Event, e.Payload, ctrl.conf.Coalesce); err != nil {
-            ctrl.Printf("error sending %s event: %s", e.Event, err)
-          }
-      }
-    case q := <-ctrl.gamechan.QueryChan:
-      ctrl.Printf("controller received game query: %s", q)
-      switch q.Query {
-        default:
-          // by default send all queries from game engine to all nodes
-          data := map[string][]byte{}
-          resp, err := ctrl.conn.Query(q.Query, q.Payload, &serf.QueryParam{FilterTags: q.Tags})
-          if err != nil {
-            q.Response <- game.NewGameQueryResponse(data, err)
-          }
-          for r := range resp.ResponseCh() {
-            data[r.From] = r.Payload
-          }
-          q.Response <- game.NewGameQueryResponse(data, err)
-      }
+      case err := <-reply:
+        if err != nil {
+          ctrl.Printf("not acking query %s: %s", q.Name, err)
+          return
+        }
+      case <-time.After(time.Until(q.Deadline())):
+        ctrl.Printf("not acking query %s: no answer from the game engine in time", q.Name)
+        return
     }
   }
+
+  if err := q.Respond([]byte(constants.QUERY_ACK)); err != nil {
+    ctrl.Printf("error responding to query %s: %s", q.Name, err)
+  }
 }
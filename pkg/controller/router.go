@@ -4,6 +4,7 @@ import (
   "os"
   "log"
   "fmt"
+  "time"
   "strings"
   "net/http"
   "path/filepath"
@@ -16,22 +17,62 @@ type PayloadForm struct {
   Payload       string      `yaml:"payload" json:"payload"`
 }
 
+type QueueMoveForm struct {
+  Position      int         `yaml:"position" json:"position"`
+}
+
 func (ctrl *Controller) Router() {
   api := ctrl.server.Router.Group("api")
   v1 := api.Group("v1")
   {
-    v1.GET("/games/:uuid", ctrl.ApiGameStats())
-    v1.POST("/do/:action", ctrl.ApiAction())
+    v1.GET("/arenas", ctrl.ApiArenas())
+    ctrl.ArenaRoutes(v1)                        // the default arena
+    ctrl.ArenaRoutes(v1.Group("arenas/:arena")) // any arena by name
+  }
+}
+
+func (ctrl *Controller) ArenaRoutes(rg *gin.RouterGroup) {
+  rg.GET("/games/:uuid", ctrl.ApiGameStats())
+  rg.GET("/games/:uuid/hits", ctrl.ApiHitRecords())
+  rg.POST("/games", ctrl.ApiNewGame())
+  rg.POST("/do/:action", ctrl.ApiAction())
+  rg.GET("/queue", ctrl.ApiQueue())
+  rg.GET("/health", ctrl.ApiHealth())
+  rg.POST("/queue", ctrl.ApiQueueGame())
+  rg.POST("/queue/:id/move", ctrl.ApiQueueMove())
+  rg.DELETE("/queue/:id", ctrl.ApiQueueCancel())
+}
+
+// ArenaFromContext is the arena named in the route, it responds with a 404 and returns nil for unknown arenas
+func (ctrl *Controller) ArenaFromContext(c *gin.Context) *Arena {
+  arena, err := ctrl.Arena(c.Param("arena"))
+  if err != nil {
+    c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s", err)})
+    return nil
+  }
+  return arena
+}
+
+func (ctrl *Controller) ApiArenas() func (*gin.Context) {
+  return func (c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{
+      "arenas": ctrl.Arenas(),
+    })
   }
 }
 
 func (ctrl *Controller) ApiGameStats() func (*gin.Context) {
   return func (c *gin.Context) {
+    arena := ctrl.ArenaFromContext(c)
+    if arena == nil {
+      return
+    }
+
     uuid := c.Param("uuid")
     switch uuid {
       case "all":
         // send listing of all archived game logs
-        entries, err := os.ReadDir(ctrl.conf.Logdir)
+        entries, err := os.ReadDir(arena.engine.Logdir())
         if err != nil {
           c.JSON(http.StatusInternalServerError, gin.H{"error": err})
           return
@@ -47,27 +88,216 @@ func (ctrl *Controller) ApiGameStats() func (*gin.Context) {
         })
         return
       case "current":
-        if !ctrl.engine.GameInProgress() {
+        if !arena.engine.GameActive() {
           c.JSON(http.StatusOK, gin.H{
             "msg": "no active game",
           })
         } else {
           // send current game stats
           c.JSON(http.StatusOK, gin.H{
-            "stats": ctrl.engine.CurrentGameState,
+            "stats": arena.engine.CurrentGameState,
+            "match": arena.engine.CurrentMatch,
           })
         }
         return
       default:
         // send archived log file
-        http.ServeFile(c.Writer, c.Request, filepath.Join(ctrl.conf.Logdir, uuid + ".json"))
+        http.ServeFile(c.Writer, c.Request, filepath.Join(arena.engine.Logdir(), uuid + ".json"))
         return
     }
   }
 }
 
+// ApiHitRecords asks the nodes for the record of every hit in a game, they keep them until the next game starts
+func (ctrl *Controller) ApiHitRecords() func (*gin.Context) {
+  return func (c *gin.Context) {
+    arena := ctrl.ArenaFromContext(c)
+    if arena == nil {
+      return
+    }
+
+    uuid := c.Param("uuid")
+    if uuid == "current" {
+      if arena.engine.CurrentGame == nil {
+        c.JSON(http.StatusOK, gin.H{
+          "msg": "no current game",
+        })
+        return
+      }
+      uuid = arena.engine.CurrentGame.Id()
+    }
+
+    records, partial, err := arena.engine.GetHitRecords(uuid)
+    if err != nil {
+      c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s", err)})
+      return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+      "hits": records,
+      "partial": partial, // some node did not answer with all of its hits
+    })
+  }
+}
+
+// ApiHealth is the last health check of every node, with any problems spotted, and the events the controller dropped
+func (ctrl *Controller) ApiHealth() func (*gin.Context) {
+  return func (c *gin.Context) {
+    arena := ctrl.ArenaFromContext(c)
+    if arena == nil {
+      return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+      "version": constants.VERSION,
+      "nodes": arena.engine.NodeHealth(),
+      "selftests": arena.engine.SelfTests(),
+      "dropped": arena.engine.Dropped.Counts(),
+    })
+  }
+}
+
+// ApiNewGame puts a game at the front of the queue, so it starts as soon as the current game is over
+func (ctrl *Controller) ApiNewGame() func (*gin.Context) {
+  return func (c *gin.Context) {
+    arena := ctrl.ArenaFromContext(c)
+    if arena == nil {
+      return
+    }
+
+    if !ctrl.conf.EnableApiActions {
+      c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s", constants.ERR_API_ACTIONS_NOT_ALLOWED)})
+      return
+    }
+
+    qg := &game.QueuedGame{}
+    if err := c.ShouldBindJSON(qg); err != nil {
+      c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s", err)})
+      return
+    }
+    qg.StartAt = time.Time{}
+    qg.AfterSeconds = 0
+
+    if err := arena.engine.QueueGame(qg); err != nil {
+      log.Printf("cannot create game %s: %s", qg.Mode, err)
+      c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s", err)})
+      return
+    }
+
+    // not found means the engine was free and has already picked it up
+    if err := arena.engine.Queue.Move(qg.Id, 0); err != nil && err != constants.ERR_QUEUED_GAME_NOT_FOUND {
+      c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s", err)})
+      return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+      "queued": qg,
+    })
+  }
+}
+
+func (ctrl *Controller) ApiQueue() func (*gin.Context) {
+  return func (c *gin.Context) {
+    arena := ctrl.ArenaFromContext(c)
+    if arena == nil {
+      return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+      "queue": arena.engine.Queue.List(),
+    })
+  }
+}
+
+func (ctrl *Controller) ApiQueueGame() func (*gin.Context) {
+  return func (c *gin.Context) {
+    arena := ctrl.ArenaFromContext(c)
+    if arena == nil {
+      return
+    }
+
+    if !ctrl.conf.EnableApiActions {
+      c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s", constants.ERR_API_ACTIONS_NOT_ALLOWED)})
+      return
+    }
+
+    qg := &game.QueuedGame{}
+    if err := c.ShouldBindJSON(qg); err != nil {
+      c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s", err)})
+      return
+    }
+
+    if err := arena.engine.QueueGame(qg); err != nil {
+      log.Printf("cannot queue game %s: %s", qg.Mode, err)
+      c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s", err)})
+      return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+      "queued": qg,
+    })
+  }
+}
+
+func (ctrl *Controller) ApiQueueMove() func (*gin.Context) {
+  return func (c *gin.Context) {
+    arena := ctrl.ArenaFromContext(c)
+    if arena == nil {
+      return
+    }
+
+    if !ctrl.conf.EnableApiActions {
+      c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s", constants.ERR_API_ACTIONS_NOT_ALLOWED)})
+      return
+    }
+
+    var moveForm QueueMoveForm
+    if err := c.ShouldBindJSON(&moveForm); err != nil {
+      c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s", err)})
+      return
+    }
+
+    if err := arena.engine.Queue.Move(c.Param("id"), moveForm.Position); err != nil {
+      c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s", err)})
+      return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+      "queue": arena.engine.Queue.List(),
+    })
+  }
+}
+
+func (ctrl *Controller) ApiQueueCancel() func (*gin.Context) {
+  return func (c *gin.Context) {
+    arena := ctrl.ArenaFromContext(c)
+    if arena == nil {
+      return
+    }
+
+    if !ctrl.conf.EnableApiActions {
+      c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("%s", constants.ERR_API_ACTIONS_NOT_ALLOWED)})
+      return
+    }
+
+    if err := arena.engine.Queue.Cancel(c.Param("id")); err != nil {
+      c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s", err)})
+      return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+      "queue": arena.engine.Queue.List(),
+    })
+  }
+}
+
 func (ctrl *Controller) ApiAction() func (*gin.Context) {
   return func (c *gin.Context) {
+    arena := ctrl.ArenaFromContext(c)
+    if arena == nil {
+      return
+    }
+
     action := c.Param("action")
 
     if !ctrl.conf.EnableApiActions {
@@ -77,7 +307,7 @@ func (ctrl *Controller) ApiAction() func (*gin.Context) {
 
     var payForm PayloadForm
     c.ShouldBindJSON(&payForm)
-    err := ctrl.ActionFromUi(action, payForm.Payload)
+    err := ctrl.ActionFromUi(arena, action, payForm.Payload)
 
     if err != nil {
       log.Printf("cannot perform api action %s: %s", action, err)
@@ -91,20 +321,35 @@ func (ctrl *Controller) ApiAction() func (*gin.Context) {
   }
 }
 
-func (ctrl *Controller) ActionFromUi(action, payload string) error {
+func (ctrl *Controller) ActionFromUi(arena *Arena, action, payload string) error {
   switch action {
     case "ui:game:mode":
-      if ctrl.engine.GameInProgress() {
+      if arena.engine.GameActive() {
         return constants.ERR_ONGOING_GAME
       } 
 
-      return ctrl.engine.SendEventToNodes(game.NewGameEvent(constants.GAME_MODE, []byte(payload)))
+      return arena.engine.SendEventToNodes(game.NewGameEvent(constants.GAME_MODE, []byte(payload)))
 // TODO: how to properly async start the game and attach to waitgroup
 //    case "ui:game:begin":
 //      go ctrl.game.Run(ctrl.conf.ExpectNodes, ctrl.conf.Timeout)
 //      return ctrl.game.SendAction(constants.GAME_ACTION_BEGIN, "web: Start the game!")
+    case "ui:game:pause":
+      return arena.SendEventToEngine(game.NewGameEvent(constants.GAME_ACTION_PAUSE, []byte("web: " + payload)))
+    case "ui:game:resume":
+      return arena.SendEventToEngine(game.NewGameEvent(constants.GAME_ACTION_RESUME, []byte("web: " + payload)))
     case "ui:game:end":
-      return ctrl.engine.EndGame()
+      return arena.SendEventToEngine(game.NewGameEvent(constants.GAME_ACTION_END, []byte("web: " + payload)))
+    case "ui:node:selftest":
+      return arena.engine.SelfTest(payload) // all nodes without a node name
+    case "ui:node:identify":
+      return arena.engine.Identify(payload)
+    case "ui:node:sensor":
+      node, update, _ := strings.Cut(payload, constants.SPLIT) // <node>:<sensor update>
+      return arena.engine.UpdateSensor(node, update)
+    case "ui:recovery:resume":
+      return arena.engine.DecideRecovery(constants.RECOVERY_RESUME)
+    case "ui:recovery:abort":
+      return arena.engine.DecideRecovery(constants.RECOVERY_ABORT)
     default:
       return constants.ERR_UI_ACTION_NOT_ALLOWED
   }
@@ -0,0 +1,181 @@
+package game
+
+import (
+  "log"
+  "time"
+  "context"
+
+  "github.com/taemon1337/arena-nerf/pkg/common"
+  "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// GameBase is what every game mode shares, its id, mode, config and channels along with
+// the game loop (see Run), game modes embed it and only handle their own events
+type GameBase struct {
+  id            string
+  mode          string
+  conf          *config.Config
+  gamechan      *GameChannel
+  clock         common.Clock
+  *log.Logger
+}
+
+func NewGameBase(id, mode string, cfg *config.Config, gamechan *GameChannel, logger *log.Logger) GameBase {
+  return GameBase{
+    id:             id,
+    mode:           mode,
+    conf:           cfg,
+    gamechan:       gamechan,
+    clock:          common.NewRealClock(),
+    Logger:         logger,
+  }
+}
+
+func (g *GameBase) Id() string {
+  return g.id
+}
+
+func (g *GameBase) Mode() string {
+  return g.mode
+}
+
+// SetClock swaps the clock the game's timers run on (see GameEngine.SetClock)
+func (g *GameBase) SetClock(clock common.Clock) {
+  g.clock = clock
+}
+
+// NewTimer returns a started timer on the game's clock
+func (g *GameBase) NewTimer(delay time.Duration, repeat bool, fire func()) *GameTimer {
+  t := g.NewStoppedTimer(delay, repeat, fire)
+  t.Reset()
+  return t
+}
+
+// NewStoppedTimer returns a timer on the game's clock that does not fire until it is reset
+func (g *GameBase) NewStoppedTimer(delay time.Duration, repeat bool, fire func()) *GameTimer {
+  return &GameTimer{clock: g.clock, delay: delay, repeat: repeat, fire: fire}
+}
+
+// Begin tells the engine the game has begun
+func (g *GameBase) Begin(msg string) {
+  g.gamechan.RequestChan <- NewGameEvent(constants.GAME_ACTION_BEGIN, []byte(msg))
+}
+
+// Request sends a request to the engine
+func (g *GameBase) Request(event, msg string) {
+  g.gamechan.RequestChan <- NewGameEvent(event, []byte(msg))
+}
+
+// Run hands each game event to handle and fires the timer (if any) until the game is shut down,
+// the timer is held while the game is paused
+func (g *GameBase) Run(ctx context.Context, timer *GameTimer, handle func(evt GameEvent)) error {
+  for {
+    select {
+    case evt := <-g.gamechan.GameChan:
+      switch evt.Event {
+        case constants.GAME_ACTION_OFF:
+          g.Printf("shutting down game by event %s", evt.Event)
+          return nil
+        case constants.GAME_ACTION_PAUSE:
+          g.Printf("game paused - holding timers")
+          timer.Hold()
+        case constants.GAME_ACTION_RESUME:
+          g.Printf("game resumed - releasing timers")
+          timer.Release()
+        default:
+          if handle == nil {
+            g.Printf("unrecognized %s event: %s", g.mode, evt.Event)
+            continue
+          }
+          handle(evt)
+      }
+    case <-timer.C():
+      timer.Fire()
+    case <-ctx.Done():
+      return ctx.Err()
+    }
+  }
+}
+
+func (g *GameBase) Stop(ctx context.Context) error {
+  ctx.Done()
+  return ctx.Err()
+}
+
+// GameTimer calls fire once the delay is up, again every delay if it repeats, a nil timer never fires,
+// it is held while the game is paused and picks up with the time it had left (see Hold)
+type GameTimer struct {
+  clock         common.Clock
+  delay         time.Duration
+  repeat        bool
+  fire          func()
+  armed         bool
+  held          bool
+  at            time.Time       // when an armed timer fires
+  left          time.Duration   // what an armed timer had left when it was held
+  c             <-chan time.Time
+}
+
+// C is the channel the timer fires on (nil while stopped or held)
+func (t *GameTimer) C() <-chan time.Time {
+  if t == nil {
+    return nil
+  }
+  return t.c
+}
+
+// Reset starts the delay over
+func (t *GameTimer) Reset() {
+  t.armed = true
+  if t.held {
+    t.left = t.delay
+    return
+  }
+  t.at = t.clock.Now().Add(t.delay)
+  t.c = t.clock.After(t.delay)
+}
+
+// ResetAfter starts the timer over with a new delay
+func (t *GameTimer) ResetAfter(delay time.Duration) {
+  t.delay = delay
+  t.Reset()
+}
+
+func (t *GameTimer) Stop() {
+  t.armed = false
+  t.c = nil
+}
+
+// Hold stops the time running out while the game is paused
+func (t *GameTimer) Hold() {
+  if t == nil || t.held {
+    return
+  }
+  t.held = true
+  t.c = nil
+  if t.armed {
+    t.left = max(t.at.Sub(t.clock.Now()), 0)
+  }
+}
+
+// Release starts the time running again with what was left when it was held
+func (t *GameTimer) Release() {
+  if t == nil || !t.held {
+    return
+  }
+  t.held = false
+  if t.armed {
+    t.at = t.clock.Now().Add(t.left)
+    t.c = t.clock.After(t.left)
+  }
+}
+
+// Fire re-arms a repeating timer (stops any other) then calls fire, which may reset it again
+func (t *GameTimer) Fire() {
+  t.Stop()
+  if t.repeat {
+    t.Reset()
+  }
+  t.fire()
+}
@@ -0,0 +1,126 @@
+package game
+
+import (
+  "log"
+  "time"
+  "testing"
+  "github.com/taemon1337/arena-nerf/pkg/common"
+  "github.com/taemon1337/arena-nerf/pkg/config"
+)
+
+func newTestGameBase(clock common.Clock) *GameBase {
+  g := NewGameBase("game1", "test", config.NewConfig(log.Default()), NewGameChannel(), log.Default())
+  g.SetClock(clock)
+  return &g
+}
+
+// fired fires the timer if it is due, as the game loop would
+func fired(timer *GameTimer) bool {
+  select {
+    case <-timer.C():
+      timer.Fire()
+      return true
+    default:
+      return false
+  }
+}
+
+func TestGameTimerRepeats(t *testing.T) {
+  clock := common.NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+  g := newTestGameBase(clock)
+
+  fires := 0
+  timer := g.NewTimer(10 * time.Second, true, func() { fires += 1 })
+
+  clock.Advance(9 * time.Second)
+  if fired(timer) {
+    t.Fatalf("fired after 9s of 10s")
+  }
+
+  for i := 1; i <= 3; i++ {
+    clock.Advance(10 * time.Second)
+    if !fired(timer) {
+      t.Fatalf("did not fire on repeat %d", i)
+    }
+    clock.Advance(0) // no time passed, no fire
+    if fired(timer) {
+      t.Fatalf("fired twice on repeat %d", i)
+    }
+  }
+
+  if fires != 3 {
+    t.Fatalf("fired %d times, want 3", fires)
+  }
+}
+
+func TestGameTimerReset(t *testing.T) {
+  clock := common.NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+  g := newTestGameBase(clock)
+
+  timer := g.NewStoppedTimer(10 * time.Second, false, func() {})
+  clock.Advance(time.Minute)
+  if fired(timer) {
+    t.Fatalf("stopped timer fired")
+  }
+
+  timer.Reset()
+  clock.Advance(5 * time.Second)
+  timer.Reset() // i.e. a target hit starts the timeout over
+  clock.Advance(5 * time.Second)
+  if fired(timer) {
+    t.Fatalf("fired 5s after being reset")
+  }
+
+  clock.Advance(5 * time.Second)
+  if !fired(timer) {
+    t.Fatalf("did not fire 10s after being reset")
+  }
+
+  clock.Advance(time.Minute)
+  if fired(timer) {
+    t.Fatalf("timer fired again without repeating")
+  }
+}
+
+func TestGameTimerHold(t *testing.T) {
+  clock := common.NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+  g := newTestGameBase(clock)
+
+  timer := g.NewTimer(10 * time.Second, true, func() {})
+  clock.Advance(4 * time.Second)
+
+  // paused 4s in, a long pause does not use up the other 6s
+  timer.Hold()
+  clock.Advance(time.Minute)
+  if fired(timer) {
+    t.Fatalf("fired while held")
+  }
+
+  timer.Release()
+  clock.Advance(5 * time.Second)
+  if fired(timer) {
+    t.Fatalf("fired 9s into 10s")
+  }
+  clock.Advance(1 * time.Second)
+  if !fired(timer) {
+    t.Fatalf("did not fire 10s in, not counting the pause")
+  }
+
+  // reset while held, i.e. a hit that landed just as the game paused
+  timer.Hold()
+  timer.Reset()
+  clock.Advance(time.Minute)
+  if fired(timer) {
+    t.Fatalf("fired after a reset while held")
+  }
+  timer.Release()
+  clock.Advance(10 * time.Second)
+  if !fired(timer) {
+    t.Fatalf("did not fire the full delay after being released")
+  }
+
+  // a nil timer (games without one) can be held and released
+  var none *GameTimer
+  none.Hold()
+  none.Release()
+}
@@ -0,0 +1,257 @@
+package game
+
+import (
+  "os"
+  "time"
+  "context"
+  "strings"
+  "path/filepath"
+  "encoding/json"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// Checkpoint is an unfinished game, saved so it can be recovered if the controller dies
+type Checkpoint struct {
+  GameId            string          `yaml:"game_id" json:"game_id"`
+  Mode              string          `yaml:"mode" json:"mode"`
+  SavedAt           time.Time       `yaml:"saved_at" json:"saved_at"`
+  State             json.RawMessage `yaml:"state" json:"state"`
+}
+
+func (ge *GameEngine) Checkpointfile() string {
+  return filepath.Join(ge.conf.Logdir, constants.CHECKPOINT_FILE)
+}
+
+// Checkpoint saves the current game, errors are only logged as the game can go on without it
+func (ge *GameEngine) Checkpoint() {
+  if ge.conf.Logdir == "" || ge.CurrentGame == nil {
+    return
+  }
+
+  state, err := json.Marshal(ge.CurrentGameState)
+  if err != nil {
+    ge.Printf("could not checkpoint game: %s", err)
+    return
+  }
+
+  data, err := json.Marshal(Checkpoint{
+    GameId:   ge.CurrentGame.Id(),
+    Mode:     ge.CurrentGame.Mode(),
+    SavedAt:  ge.clock.Now(),
+    State:    state,
+  })
+  if err != nil {
+    ge.Printf("could not checkpoint game: %s", err)
+    return
+  }
+
+  // write then rename, so dying mid-write never leaves a broken checkpoint
+  tmp := ge.Checkpointfile() + ".tmp"
+  if err := os.WriteFile(tmp, data, os.ModePerm); err != nil {
+    ge.Printf("could not checkpoint game: %s", err)
+    return
+  }
+
+  if err := os.Rename(tmp, ge.Checkpointfile()); err != nil {
+    ge.Printf("could not checkpoint game: %s", err)
+  }
+}
+
+func (ge *GameEngine) RemoveCheckpoint() {
+  if ge.conf.Logdir == "" {
+    return
+  }
+
+  if err := os.Remove(ge.Checkpointfile()); err != nil && !os.IsNotExist(err) {
+    ge.Printf("could not remove game checkpoint: %s", err)
+  }
+}
+
+func (ge *GameEngine) LoadCheckpoint() (*Checkpoint, error) {
+  if ge.conf.Logdir == "" {
+    return nil, constants.ERR_NO_CHECKPOINT
+  }
+
+  data, err := os.ReadFile(ge.Checkpointfile())
+  if os.IsNotExist(err) {
+    return nil, constants.ERR_NO_CHECKPOINT
+  }
+  if err != nil {
+    return nil, err
+  }
+
+  cp := &Checkpoint{}
+  if err := json.Unmarshal(data, cp); err != nil {
+    return nil, err
+  }
+  return cp, nil
+}
+
+// RecoverGame resumes or aborts (by -recovery) a game left unfinished when the controller died,
+// returning true if the game was resumed and played out
+func (ge *GameEngine) RecoverGame(ctx context.Context) (bool, error) {
+  cp, err := ge.LoadCheckpoint()
+  if err == constants.ERR_NO_CHECKPOINT {
+    return false, nil
+  }
+  if err != nil {
+    ge.Printf("cannot read game checkpoint: %s", err)
+    return false, err
+  }
+
+  ge.Printf("found unfinished %s game %s (saved at %s)", cp.Mode, cp.GameId, cp.SavedAt.Format(time.RFC3339))
+
+  decision := ge.conf.Recovery
+  if decision == constants.RECOVERY_ASK && !(ge.conf.EnableServer && ge.conf.EnableApiActions) {
+    ge.Printf("cannot ask whether to recover without the server and api actions, aborting the game")
+    decision = constants.RECOVERY_ABORT
+  }
+
+  if decision == constants.RECOVERY_ASK {
+    ge.Printf("waiting for ui:recovery:resume or ui:recovery:abort")
+    select {
+      case decision = <-ge.recovery:
+      case <-ctx.Done():
+        return false, ctx.Err()
+    }
+  }
+
+  if decision == constants.RECOVERY_RESUME {
+    return true, ge.RestoreGame(ctx, cp)
+  }
+  return false, ge.AbortGame(cp)
+}
+
+// DecideRecovery answers a RecoverGame that is waiting to ask
+func (ge *GameEngine) DecideRecovery(decision string) error {
+  select {
+    case ge.recovery <- decision:
+      return nil
+    default:
+      return constants.ERR_NO_CHECKPOINT
+  }
+}
+
+// MountCheckpoint loads the checkpointed game and its state
+func (ge *GameEngine) MountCheckpoint(cp *Checkpoint) error {
+  g := NewGameWithId(cp.GameId, cp.Mode, ge.conf, ge.gamechan, ge.Logger)
+  if g == nil {
+    return constants.ERR_UNSUPPORTED_GAME_MODE
+  }
+
+  if err := ge.MountGame(g); err != nil {
+    return err
+  }
+
+  if err := json.Unmarshal(cp.State, ge.CurrentGameState); err != nil {
+    return err
+  }
+
+  ge.CurrentGameState.Restore(cp.SavedAt)
+  return nil
+}
+
+// RestoreGame catches the checkpointed game up with the nodes and plays it out,
+// the time the controller was down does not count against the game clock
+func (ge *GameEngine) RestoreGame(ctx context.Context, cp *Checkpoint) error {
+  saved := &GameState{}
+  if err := json.Unmarshal(cp.State, saved); err != nil {
+    ge.Printf("cannot restore game %s: %s", cp.GameId, err)
+    return err
+  }
+
+  if err := ge.MountCheckpoint(cp); err != nil {
+    ge.Printf("cannot restore game %s: %s", cp.GameId, err)
+    return err
+  }
+
+  expect := len(ge.conf.Nodes)
+
+  if err := ge.WaitForNodes(expect, ge.conf.Timeout); err != nil {
+    ge.Printf("error waiting for game nodes: %s", err)
+    return constants.ERR_NODES_NOT_READY
+  }
+
+  if err := ge.ResetNodes(expect, true); err != nil {
+    ge.Printf("error resetting nodes: %s", err)
+    return err
+  }
+
+  if err := ge.WaitForGameModeSetup(ge.CurrentGame.Mode()); err != nil {
+    ge.Printf("error sending game mode to nodes: %s", err)
+    return err
+  }
+
+  if err := ge.SendRulesToNodes(); err != nil {
+    ge.Printf("error sending game rules to nodes: %s", err)
+    return err
+  }
+
+  // setting the mode brings every sensor back, so take the eliminated ones down again
+  for _, target := range ge.CurrentGameState.Targets {
+    if target.Eliminated {
+      evt := strings.Join([]string{target.Node, constants.TARGET_DOWN}, constants.SPLIT)
+      if err := ge.SendEventToNodes(NewGameEvent(evt, []byte(target.Sensor))); err != nil {
+        return err
+      }
+    }
+  }
+
+  raw, nodeboard, hitboard, err := ge.GetScoreboard()
+  if err != nil {
+    ge.Printf("error compiling node scores: %s", err)
+    return err
+  }
+  ge.CurrentGameState.SetRawScoreboard(raw)
+  ge.CurrentGameState.SetHitboard(hitboard)
+  scoreboard := ge.CurrentGameState.config.Handicap(raw)
+  ge.CurrentGameState.SetBoards(scoreboard, nodeboard)
+
+  // the reset left the nodes waiting for the game to begin, and a game paused when the controller went down
+  // comes back running (see GameState.Restore) so its sensors are taken out of the paused pattern too
+  if err := ge.SendEventToNodes(NewGameEvent(constants.GAME_ACTION_BEGIN, []byte("restored after controller restart"))); err != nil {
+    ge.Printf("error telling nodes to start game: %s", err)
+    return err
+  }
+
+  evt := NewGameEvent(constants.GAME_ACTION_RESUME, []byte("restored after controller restart"))
+  if saved.Status == constants.GAME_STATUS_PAUSED {
+    if err := ge.SendEventToNodes(evt); err != nil {
+      ge.Printf("error telling nodes to resume game: %s", err)
+      return err
+    }
+  }
+  ge.CurrentGameState.LogGameEvent(evt)
+  ge.Checkpoint()
+
+  ge.Printf("%s (restored):\n---\n%s", ge.CurrentGame, ge.CurrentGameState)
+
+  ge.Printf("passing control to game - %s", ge.CurrentGame)
+  return ge.CurrentGame.Start(ctx)
+}
+
+// AbortGame logs the checkpointed game as aborted
+func (ge *GameEngine) AbortGame(cp *Checkpoint) error {
+  if err := ge.MountCheckpoint(cp); err != nil {
+    ge.Printf("cannot load game %s: %s", cp.GameId, err)
+    return err
+  }
+
+  ge.Printf("aborting game %s", cp.GameId)
+  ge.CurrentGameState.Abort()
+  defer ge.CloseGameOver()
+
+  if err := ge.SendEventToNodes(NewGameEvent(constants.GAME_ACTION_END, []byte("The game was aborted."))); err != nil {
+    ge.Printf("error sending game ended event: %s", err)
+    return err
+  }
+
+  if err := ge.LogGame(); err != nil {
+    return err
+  }
+
+  ge.RemoveCheckpoint()
+  ge.CurrentGame = nil
+  ge.CurrentGameState = NewGameState(NewGameConfig(ge.conf))
+  return nil
+}
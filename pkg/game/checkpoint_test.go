@@ -0,0 +1,149 @@
+package game
+
+import (
+  "os"
+  "fmt"
+  "time"
+  "context"
+  "testing"
+  "encoding/json"
+  "path/filepath"
+  "github.com/taemon1337/arena-nerf/pkg/common"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// checkpointGame leaves a paused drill checkpointed in logdir, as if the controller died there
+func checkpointGame(t *testing.T, clock *common.ManualClock, logdir string) string {
+  ge := newTestGameEngine()
+  ge.conf.Logdir = logdir
+  ge.SetClock(clock)
+  if err := ge.NewGame(constants.GAME_MODE_DRILL, nil); err != nil {
+    t.Fatalf("cannot mount game: %s", err)
+  }
+  if err := ge.CurrentGameState.GameSetup(); err != nil {
+    t.Fatalf("cannot set game up: %s", err)
+  }
+  clock.Advance(time.Minute)
+  if err := ge.CurrentGameState.Pause(); err != nil {
+    t.Fatalf("cannot pause game: %s", err)
+  }
+  ge.Checkpoint()
+  return ge.CurrentGame.Id()
+}
+
+// recoveringEngine is the restarted controller's engine, with nodes that are ready for the game
+func recoveringEngine(t *testing.T, clock *common.ManualClock, logdir, recovery, gameid string) *GameEngine {
+  ge := newTestGameEngine()
+  ge.conf.Logdir = logdir
+  ge.conf.Recovery = recovery
+  ge.SetClock(clock)
+
+  answers := map[string]map[string][]byte{}
+  for query, answer := range map[string]string{
+    constants.NODE_READY:         constants.NODE_IS_READY,
+    constants.GAME_ACTION_RESET:  gameid,
+    constants.GAME_MODE:          constants.GAME_MODE_DRILL,
+  } {
+    answers[query] = map[string][]byte{}
+    for _, node := range ge.conf.Nodes {
+      answers[query][node] = []byte(answer)
+    }
+  }
+  answerNodes(t, ge, answers)
+  return ge
+}
+
+func TestRecoverGameNoCheckpoint(t *testing.T) {
+  ge := newTestGameEngine()
+  ge.conf.Logdir = t.TempDir()
+  if resumed, err := ge.RecoverGame(context.Background()); resumed || err != nil {
+    t.Fatalf("recovered %t with error %v without a checkpoint", resumed, err)
+  }
+}
+
+func TestRecoverGameAbort(t *testing.T) {
+  for _, recovery := range []string{constants.RECOVERY_ABORT, constants.RECOVERY_ASK} {
+    clock := common.NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+    logdir := t.TempDir()
+    gameid := checkpointGame(t, clock, logdir)
+    clock.Advance(10 * time.Minute)
+
+    // with no server to ask, ask aborts too
+    ge := recoveringEngine(t, clock, logdir, recovery, gameid)
+    resumed, err := ge.RecoverGame(context.Background())
+    if resumed || err != nil {
+      t.Fatalf("%s: recovered %t with error %v, want the game aborted", recovery, resumed, err)
+    }
+
+    if _, err := os.Stat(ge.Checkpointfile()); !os.IsNotExist(err) {
+      t.Errorf("%s: checkpoint left behind", recovery)
+    }
+    if ge.CurrentGame != nil {
+      t.Errorf("%s: aborted game still mounted", recovery)
+    }
+    select {
+      case <-ge.gameover:
+      default:
+        t.Errorf("%s: game over not closed", recovery)
+    }
+
+    data, err := os.ReadFile(filepath.Join(logdir, fmt.Sprintf("%s-%s.json", constants.GAME_MODE_DRILL, gameid)))
+    if err != nil {
+      t.Fatalf("%s: aborted game not logged: %s", recovery, err)
+    }
+    logged := &GameState{}
+    if err := json.Unmarshal(data, logged); err != nil {
+      t.Fatalf("%s: cannot read game log: %s", recovery, err)
+    }
+    if logged.Status != constants.GAME_STATUS_ABORTED {
+      t.Errorf("%s: logged game %s, want %s", recovery, logged.Status, constants.GAME_STATUS_ABORTED)
+    }
+  }
+}
+
+func TestRecoverGameResume(t *testing.T) {
+  clock := common.NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+  logdir := t.TempDir()
+  gameid := checkpointGame(t, clock, logdir)
+  clock.Advance(10 * time.Minute)
+
+  ge := recoveringEngine(t, clock, logdir, constants.RECOVERY_RESUME, gameid)
+  ctx, cancel := context.WithCancel(context.Background())
+  done := make(chan error, 1)
+  go func() {
+    resumed, err := ge.RecoverGame(ctx)
+    if !resumed {
+      err = fmt.Errorf("game not resumed: %v", err)
+    }
+    done <- err
+  }()
+
+  // the restored game is played out from where it was
+  select {
+    case evt := <-ge.gamechan.RequestChan:
+      if evt.Event != constants.GAME_ACTION_BEGIN {
+        t.Fatalf("restored game sent %s, want %s", evt, constants.GAME_ACTION_BEGIN)
+      }
+    case err := <-done:
+      t.Fatalf("game not restored: %v", err)
+    case <-time.After(time.Second):
+      t.Fatalf("restored game never began")
+  }
+
+  cancel()
+  if err := <-done; err != context.Canceled {
+    t.Fatalf("restored game stopped with %v", err)
+  }
+
+  gs := ge.CurrentGameState
+  if ge.CurrentGame.Id() != gameid || gs.Status != constants.GAME_STATUS_RUNNING {
+    t.Fatalf("restored game %s %s, want %s running", ge.CurrentGame.Id(), gs.Status, gameid)
+  }
+  // neither the pause nor the time the controller was down count against the game
+  if gs.PausedDuration != 10 * time.Minute || !gs.PausedAt.IsZero() {
+    t.Fatalf("restored game paused for %s, want 10m0s", gs.PausedDuration)
+  }
+  if _, err := ge.LoadCheckpoint(); err != nil {
+    t.Fatalf("restored game not checkpointed: %s", err)
+  }
+}
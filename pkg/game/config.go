@@ -1,19 +1,147 @@
 package game
 
 import (
+  "maps"
+  "time"
+  "slices"
+  "github.com/taemon1337/arena-nerf/pkg/common"
   "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
 )
 
 type GameConfig struct {
   Cfg                       *config.Config  `yaml:"config" json:"config"`
   GameLength                string          `yaml:"game_length" json:"game_length"`
   WinningScore              int             `yaml:"winning_score" json:"winning_score"`
-  MinNodeCount              int             `yaml:"max_node_count" json:"max_node_count"`
-  MaxNodeCount              int             `yaml:"min_node_count" json:"min_node_count"`
+  MinNodeCount              int             `yaml:"min_node_count" json:"min_node_count"`
+  MaxNodeCount              int             `yaml:"max_node_count" json:"max_node_count"`
   MinTeamCount              int             `yaml:"min_team_count" json:"min_team_count"`
   MaxTeamCount              int             `yaml:"max_team_count" json:"max_team_count"`
   RequiredNodeNames         []string        `yaml:"required_node_names" json:"required_node_names"`
   RequiredTeamNames         []string        `yaml:"required_team_names" json:"required_team_names"`
+  TargetHealth              int             `yaml:"target_health" json:"target_health"`
+  CaptureBonus              int             `yaml:"capture_bonus" json:"capture_bonus"`
+  TargetColor               string          `yaml:"target_color" json:"target_color"`
+  DrillTargets              int             `yaml:"drill_targets" json:"drill_targets"`
+  Player                    string          `yaml:"player" json:"player"`
+  PointsPerHit              int             `yaml:"points_per_hit" json:"points_per_hit"`
+  SensorPoints              map[string]int  `yaml:"sensor_points" json:"sensor_points"`
+  Civilians                 []string        `yaml:"civilians" json:"civilians"`
+  CivilianPoints            int             `yaml:"civilian_points" json:"civilian_points"`
+  StreakHits                int             `yaml:"streak_hits" json:"streak_hits"`
+  StreakWindow              string          `yaml:"streak_window" json:"streak_window"`
+  StreakBonus               int             `yaml:"streak_bonus" json:"streak_bonus"`
+  Lighting                  string          `yaml:"lighting" json:"lighting"`
+  EndConditions             []string        `yaml:"end_conditions" json:"end_conditions"`
+  TieBreak                  string          `yaml:"tie_break" json:"tie_break"`
+  Overtime                  string          `yaml:"overtime" json:"overtime"`
+  Handicaps                 map[string]*config.Handicap `yaml:"handicaps" json:"handicaps"`
+  Course                    []string        `yaml:"course" json:"course"` // nil unless the game mode runs a course
+  PenaltySeconds            int             `yaml:"penalty_seconds" json:"penalty_seconds"`
+  Countdown                 int             `yaml:"countdown" json:"countdown"`
+  Seed                      int64           `yaml:"seed" json:"seed"`
+  Clock                     common.Clock    `yaml:"-" json:"-"`
+}
+
+// GameOverrides change the rules of a single game, anything left empty keeps what the game mode configured
+type GameOverrides struct {
+  GameLength                string          `yaml:"game_length" json:"game_length"`
+  WinningScore              int             `yaml:"winning_score" json:"winning_score"`
+  Teams                     []string        `yaml:"teams" json:"teams"`
+  MinNodeCount              int             `yaml:"min_node_count" json:"min_node_count"`
+  MaxNodeCount              int             `yaml:"max_node_count" json:"max_node_count"`
+  MinTeamCount              int             `yaml:"min_team_count" json:"min_team_count"`
+  MaxTeamCount              int             `yaml:"max_team_count" json:"max_team_count"`
+  RequiredNodeNames         []string        `yaml:"required_node_names" json:"required_node_names"`
+  RequiredTeamNames         []string        `yaml:"required_team_names" json:"required_team_names"`
+  Player                    string          `yaml:"player" json:"player"` // who is playing a solo game, like a drill
+  Handicaps                 map[string]*config.Handicap `yaml:"handicaps" json:"handicaps"` // by team, on top of the -handicap flags
+}
+
+// NewGameOverrides are the limits set in the config (or flags), which apply to every game
+func NewGameOverrides(cfg *config.Config) *GameOverrides {
+  return &GameOverrides{
+    MinNodeCount:       cfg.MinNodes,
+    MaxNodeCount:       cfg.MaxNodes,
+    MinTeamCount:       cfg.MinTeams,
+    MaxTeamCount:       cfg.MaxTeams,
+    RequiredNodeNames:  cfg.RequiredNodes,
+    RequiredTeamNames:  cfg.RequiredTeams,
+  }
+}
+
+// Validate checks the overrides before a game is created with them
+func (o *GameOverrides) Validate() error {
+  if o.GameLength != "" {
+    if _, err := time.ParseDuration(o.GameLength); err != nil {
+      return err
+    }
+  }
+
+  if o.WinningScore < 0 || o.MinNodeCount < 0 || o.MaxNodeCount < 0 || o.MinTeamCount < 0 || o.MaxTeamCount < 0 {
+    return constants.ERR_INVALID_GAME_OVERRIDE
+  }
+
+  if o.MaxNodeCount > 0 && o.MinNodeCount > o.MaxNodeCount {
+    return constants.ERR_INVALID_GAME_OVERRIDE
+  }
+
+  if o.MaxTeamCount > 0 && o.MinTeamCount > o.MaxTeamCount {
+    return constants.ERR_INVALID_GAME_OVERRIDE
+  }
+
+  for _, h := range o.Handicaps {
+    if h == nil || h.Multiplier < 0 || h.WinningScore < 0 {
+      return constants.ERR_INVALID_GAME_OVERRIDE
+    }
+  }
+
+  return nil
+}
+
+// Configure applies the overrides on top of the game mode's config
+func (o *GameOverrides) Configure(gc *GameConfig) {
+  if o.GameLength != "" {
+    gc.GameLength = o.GameLength
+  }
+  if o.WinningScore > 0 {
+    gc.WinningScore = o.WinningScore
+  }
+  if o.MinNodeCount > 0 {
+    gc.MinNodeCount = o.MinNodeCount
+  }
+  if o.MaxNodeCount > 0 {
+    gc.MaxNodeCount = o.MaxNodeCount
+  }
+  if o.MinTeamCount > 0 {
+    gc.MinTeamCount = o.MinTeamCount
+  }
+  if o.MaxTeamCount > 0 {
+    gc.MaxTeamCount = o.MaxTeamCount
+  }
+  if len(o.RequiredNodeNames) > 0 {
+    gc.RequiredNodeNames = o.RequiredNodeNames
+  }
+  if len(o.RequiredTeamNames) > 0 {
+    gc.RequiredTeamNames = o.RequiredTeamNames
+  }
+  if o.Player != "" {
+    gc.Player = o.Player
+  }
+  if len(o.Handicaps) > 0 {
+    // copied so the flags' handicaps are left alone for the next game
+    handicaps := map[string]*config.Handicap{}
+    maps.Copy(handicaps, gc.Handicaps)
+    for team, h := range o.Handicaps {
+      handicap := *h
+      handicap.Team = team
+      if handicap.Multiplier == 0 {
+        handicap.Multiplier = 1 // left out of the json
+      }
+      handicaps[team] = &handicap
+    }
+    gc.Handicaps = handicaps
+  }
 }
 
 func NewGameConfig(cfg *config.Config) *GameConfig {
@@ -27,5 +155,52 @@ func NewGameConfig(cfg *config.Config) *GameConfig {
     MaxTeamCount:       100,
     RequiredNodeNames:  []string{},
     RequiredTeamNames:  []string{},
+    TargetHealth:       0, // targets have no health unless the game mode uses it
+    CaptureBonus:       0,
+    TargetColor:        "", // targets are lit in a random team color unless set
+    DrillTargets:       0,
+    Player:             cfg.Player,
+    PointsPerHit:       1,
+    SensorPoints:       maps.Clone(cfg.PointsConf.Points),
+    Civilians:          cfg.Civilians,
+    CivilianPoints:     cfg.CivilianPoints,
+    StreakHits:         cfg.StreakHits,
+    StreakWindow:       cfg.StreakWindow,
+    StreakBonus:        cfg.StreakBonus,
+    Lighting:           "", // lighting is up to the game mode unless set
+    EndConditions:      []string{constants.END_ON_TIME, constants.END_ON_SCORE},
+    TieBreak:           cfg.TieBreak,
+    Overtime:           cfg.Overtime,
+    Handicaps:          cfg.HandicapsConf.Handicaps,
+    Course:             nil,
+    PenaltySeconds:     cfg.PenaltySeconds,
+    Countdown:          cfg.Countdown,
+    Seed:               cfg.Seed,
+    Clock:              common.NewRealClock(),
+  }
+}
+
+// WinningScoreFor is the score a team needs to win, which a handicap can lower (or raise)
+func (gc *GameConfig) WinningScoreFor(team string) int {
+  if h, ok := gc.Handicaps[team]; ok && h.WinningScore > 0 {
+    return h.WinningScore
   }
+  return gc.WinningScore
+}
+
+// Handicap weights the raw team scores by each team's handicap
+func (gc *GameConfig) Handicap(raw map[string]int) map[string]int {
+  scoreboard := map[string]int{}
+  for team, points := range raw {
+    if h, ok := gc.Handicaps[team]; ok {
+      scoreboard[team] = h.Score(points)
+    } else {
+      scoreboard[team] = points
+    }
+  }
+  return scoreboard
+}
+
+func (gc *GameConfig) EndsOn(condition string) bool {
+  return slices.Contains(gc.EndConditions, condition)
 }
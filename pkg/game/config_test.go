@@ -0,0 +1,32 @@
+package game
+
+import (
+  "log"
+  "maps"
+  "testing"
+  "github.com/taemon1337/arena-nerf/pkg/config"
+)
+
+func TestGameConfigHandicap(t *testing.T) {
+  gc := NewGameConfig(config.NewConfig(log.Default()))
+  gc.Handicaps = map[string]*config.Handicap{
+    "red":    {Team: "red", Bonus: 5, Multiplier: 1.5, WinningScore: 8},
+    "blue":   {Team: "blue", Bonus: 0, Multiplier: 2},
+  }
+  gc.WinningScore = 20
+
+  raw := map[string]int{"red": 3, "blue": 3, "green": 3}
+  want := map[string]int{"red": 10, "blue": 6, "green": 3}
+  if got := gc.Handicap(raw); !maps.Equal(got, want) {
+    t.Errorf("handicapped %v to %v, want %v", raw, got, want)
+  }
+  if raw["red"] != 3 {
+    t.Errorf("raw scores changed to %v", raw)
+  }
+
+  for team, want := range map[string]int{"red": 8, "blue": 20, "green": 20} {
+    if got := gc.WinningScoreFor(team); got != want {
+      t.Errorf("%s winning score %d, want %d", team, got, want)
+    }
+  }
+}
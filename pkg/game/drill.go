@@ -0,0 +1,48 @@
+package game
+
+import (
+  "log"
+  "context"
+
+  "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// GameDrill is solo practice, a single player hits a preset number of targets in the order they
+// light up while their split times, misses and accuracy are recorded under their name
+type GameDrill struct {
+  GameBase
+}
+
+func NewGameDrill(id, mode string, cfg *config.Config, gamechan *GameChannel, logger *log.Logger) *GameDrill {
+  return &GameDrill{
+    GameBase:       NewGameBase(id, mode, cfg, gamechan, log.New(logger.Writer(), "[DRILL]: ", logger.Flags())),
+  }
+}
+
+func (g *GameDrill) Configure(gc *GameConfig) {
+  gc.MinTeamCount = 1
+  gc.WinningScore = 0 // the drill is over once every target is hit (or time runs out)
+  gc.TargetColor = constants.COLOR_YELLOW
+  gc.DrillTargets = g.conf.DrillTargets
+}
+
+func (g *GameDrill) Start(ctx context.Context) error {
+  g.Printf("starting game %s (%d targets)", g, g.conf.DrillTargets) // the player is set on the game state
+  g.Begin("starting drill!")
+  g.Request(constants.RANDOM_TARGET, "lighting the first target")
+
+  return g.Run(ctx, nil, func(evt GameEvent) {
+    switch evt.Event {
+      case constants.TARGET_HIT:
+        g.Printf("target hit: %s", string(evt.Payload))
+        g.Request(constants.RANDOM_TARGET, "lighting the next target")
+      default:
+        g.Printf("unrecognized drill event: %s", evt.Event)
+    }
+  })
+}
+
+func (g *GameDrill) String() string {
+  return constants.GAME_MODE_DRILL
+}
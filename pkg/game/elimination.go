@@ -0,0 +1,43 @@
+package game
+
+import (
+  "log"
+  "time"
+  "context"
+
+  "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// GameElimination gives every sensor hit points, each hit takes one away and a sensor at zero
+// goes dark with the capture bonus going to the team that landed the final blow
+type GameElimination struct {
+  GameBase
+}
+
+func NewGameElimination(id, mode string, cfg *config.Config, gamechan *GameChannel, logger *log.Logger) *GameElimination {
+  return &GameElimination{
+    GameBase:       NewGameBase(id, mode, cfg, gamechan, log.New(logger.Writer(), "[ELIMINATION]: ", logger.Flags())),
+  }
+}
+
+func (g *GameElimination) Configure(gc *GameConfig) {
+  gc.TargetHealth = g.conf.TargetHealth
+  gc.CaptureBonus = g.conf.CaptureBonus
+}
+
+func (g *GameElimination) Start(ctx context.Context) error {
+  g.Printf("starting game %s", g)
+  g.Begin("starting elimination!")
+  g.Request(constants.RANDOM_SENSOR_COLORS, "lighting all targets")
+
+  // shuffle colors so every team gets a shot at every target
+  timer := g.NewTimer(5 * time.Second, true, func() {
+    g.Request(constants.RANDOM_SENSOR_COLORS, "shuffling target colors")
+  })
+  return g.Run(ctx, timer, nil)
+}
+
+func (g *GameElimination) String() string {
+  return constants.GAME_MODE_ELIMINATION
+}
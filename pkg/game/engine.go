@@ -2,14 +2,18 @@ package game
 
 import (
   "log"
+  "maps"
+  "regexp"
   "fmt"
   "time"
+  "sync"
   "slices"
   "strings"
   "context"
-  "math/rand"
+  "path/filepath"
   "encoding/json"
 
+  "github.com/taemon1337/arena-nerf/pkg/common"
   "github.com/taemon1337/arena-nerf/pkg/config"
   "github.com/taemon1337/arena-nerf/pkg/constants"
 )
@@ -19,41 +23,146 @@ type GameEngine struct {
   gamechan              *GameChannel
   CurrentGame           Game
   CurrentGameState      *GameState
+  CurrentMatch          *Match
+  gameover              chan struct{}   // closed once the current game is over, scored and logged or not (see CloseGameOver)
+  gameoveronce          *sync.Once
+  clock                 common.Clock
+  recovery              chan string     // answers a RecoverGame waiting to ask (see -recovery)
+  Queue                 *GameQueue
+  lastended             time.Time       // when the last game ended, queued games can start a while after it
+  health                map[string]*NodeHealth // the last answer of each node to a health check
+  selftests             map[string]*SelfTestResult // the last self test of each node
+  healthlock            *sync.Mutex
+  Dropped               *common.Counter // events lost to a full channel
   *log.Logger
 }
 
 func NewGameEngine(cfg *config.Config, gamechan *GameChannel, logger *log.Logger) *GameEngine {
+  prefix := "[GAME]: "
+  if cfg.Arena != "" {
+    prefix = fmt.Sprintf("[GAME %s]: ", cfg.Arena)
+  }
+
   return &GameEngine{
     conf:               cfg,
     gamechan:           gamechan,
     CurrentGame:        nil,
     CurrentGameState:   NewGameState(NewGameConfig(cfg)),
-    Logger:             log.New(logger.Writer(), "[GAME]: ", logger.Flags()),
+    CurrentMatch:       nil,
+    gameover:           make(chan struct{}),
+    gameoveronce:       &sync.Once{},
+    clock:              common.NewRealClock(),
+    recovery:           make(chan string),
+    Queue:              NewGameQueue(),
+    lastended:          time.Time{},
+    health:             map[string]*NodeHealth{},
+    selftests:          map[string]*SelfTestResult{},
+    healthlock:         &sync.Mutex{},
+    Dropped:            common.NewCounter(),
+    Logger:             log.New(logger.Writer(), prefix, logger.Flags()),
+  }
+}
+
+// Arena is the arena the engine plays games in, empty when the controller has no arenas
+func (ge *GameEngine) Arena() string {
+  return ge.conf.Arena
+}
+
+func (ge *GameEngine) Logdir() string {
+  return ge.conf.Logdir
+}
+
+// NodeTags select the nodes of the engine's arena for queries
+func (ge *GameEngine) NodeTags() map[string]string {
+  if ge.conf.Arena == "" {
+    return constants.NODE_TAGS
   }
+
+  tags := maps.Clone(constants.NODE_TAGS)
+  tags[constants.TAG_ARENA] = "^" + regexp.QuoteMeta(ge.conf.Arena) + "$" // filter tags are regexes, so north must not match northeast
+  return tags
+}
+
+// SetClock swaps the real clock for another (i.e. a manual one) for every game mounted after
+func (ge *GameEngine) SetClock(clock common.Clock) {
+  ge.clock = clock
 }
 
-func (ge *GameEngine) NewGame(mode string) error {
-  newgame := NewGame(mode, ge.conf, ge.gamechan, ge.Logger)
-  return ge.MountGame(newgame)
+// NewGame creates and mounts a game, the overrides (if any) change its rules from what the game mode configured
+func (ge *GameEngine) NewGame(mode string, overrides *GameOverrides) error {
+  if overrides == nil {
+    overrides = &GameOverrides{}
+  }
+
+  if err := overrides.Validate(); err != nil {
+    return err
+  }
+
+  cfg := ge.conf.ForGame(overrides.Teams, overrides.GameLength)
+  newgame := NewGame(mode, cfg, ge.gamechan, ge.Logger)
+  if newgame == nil {
+    return constants.ERR_UNSUPPORTED_GAME_MODE
+  }
+  return ge.MountGameWithConfig(newgame, cfg, overrides)
 }
 
 func (ge *GameEngine) GameInProgress() bool {
   return ge.CurrentGame != nil && ge.CurrentGameState.Running()
 }
 
+// GameActive is true while a game is running or paused
+func (ge *GameEngine) GameActive() bool {
+  return ge.CurrentGame != nil && (ge.CurrentGameState.Running() || ge.CurrentGameState.Paused())
+}
+
+// Busy is true from the moment a game is mounted until it is over, or while a match is being played
+func (ge *GameEngine) Busy() bool {
+  if ge.CurrentMatch != nil && ge.CurrentMatch.Status == constants.GAME_STATUS_RUNNING {
+    return true
+  }
+  return ge.CurrentGame != nil && (ge.CurrentGameState.Status == constants.GAME_STATUS_INIT || ge.CurrentGameState.Status == constants.GAME_STATUS_STARTING || ge.GameActive())
+}
+
 func (ge *GameEngine) MountGame(g Game) error {
-  if ge.GameInProgress() {
+  return ge.MountGameWithConfig(g, ge.conf, nil)
+}
+
+// MountGameWithConfig mounts a game that was created with its own config (see Config.ForGame) and overrides
+func (ge *GameEngine) MountGameWithConfig(g Game, cfg *config.Config, overrides *GameOverrides) error {
+  if ge.GameActive() {
     return constants.ERR_GAME_RUNNING
   }
   ge.Printf("loading new game - %s", g)
   // TODO: save old game
+  gc := NewGameConfig(cfg)
+  gc.Clock = ge.clock
+  if c, ok := g.(GameClock); ok {
+    c.SetClock(ge.clock)
+  }
+  if c, ok := g.(GameConfigurer); ok {
+    c.Configure(gc)
+  }
+  NewGameOverrides(cfg).Configure(gc)
+  if overrides != nil {
+    overrides.Configure(gc)
+  }
   ge.CurrentGame = g
-  ge.CurrentGameState = NewGameState(NewGameConfig(ge.conf))
+  ge.CurrentGameState = NewGameState(gc)
+  ge.gameover = make(chan struct{})
+  ge.gameoveronce = &sync.Once{}
   return nil
 }
 
+// CloseGameOver lets whoever waits on the current game (i.e. a match) move on, every way a game
+// ends calls it, however far it got, and it is safe to call more than once
+func (ge *GameEngine) CloseGameOver() {
+  ge.gameoveronce.Do(func() {
+    close(ge.gameover)
+  })
+}
+
 func (ge *GameEngine) StartGame(ctx context.Context) error {
-  if ge.GameInProgress() {
+  if ge.GameActive() {
     return constants.ERR_GAME_RUNNING
   }
 
@@ -64,11 +173,21 @@ func (ge *GameEngine) StartGame(ctx context.Context) error {
     return constants.ERR_NODES_NOT_READY
   }
 
+  if err := ge.ResetNodes(expect, false); err != nil {
+    ge.Printf("error resetting nodes: %s", err)
+    return err
+  }
+
   if err := ge.WaitForGameModeSetup(ge.CurrentGame.Mode()); err != nil {
     ge.Printf("error sending game mode to nodes: %s", err)
     return err
   }
 
+  if err := ge.SendRulesToNodes(); err != nil {
+    ge.Printf("error sending game rules to nodes: %s", err)
+    return err
+  }
+
   if err := ge.CurrentGameState.GameSetup(); err != nil {
     ge.Printf("error setting game up: %s", err)
     return err
@@ -83,43 +202,142 @@ func (ge *GameEngine) StartGame(ctx context.Context) error {
     return nil // returning error will shutdown which we don't want
   }
 
+  if ge.CurrentGameState.config.TargetHealth > 0 {
+    if err := ge.SetupTargets(); err != nil {
+      ge.Printf("error setting up targets: %s", err)
+      return err
+    }
+  }
+
+  if err := ge.Countdown(ctx); err != nil {
+    return err
+  }
+
   ge.Printf("%s:\n---\n%s", ge.CurrentGame, ge.CurrentGameState)
+  ge.Checkpoint()
 
   ge.Printf("passing control to game - %s", ge.CurrentGame)
   return ge.CurrentGame.Start(ctx)
 }
 
+// Countdown tells every node when the game starts, so they all start at the same instant
+// whenever the event reaches them, and waits it out
+func (ge *GameEngine) Countdown(ctx context.Context) error {
+  seconds := ge.CurrentGameState.config.Countdown
+  if seconds <= 0 {
+    return nil
+  }
+
+  startat := ge.CurrentGameState.StartCountdown(seconds)
+  ge.Printf("game starts in %ds at %s", seconds, startat.Format(time.RFC3339Nano))
+
+  evt := NewGameEvent(constants.GAME_ACTION_COUNTDOWN, []byte(fmt.Sprintf("%d", startat.UnixMilli())))
+  ge.CurrentGameState.LogGameEvent(evt)
+  if err := ge.SendEventToNodes(evt); err != nil {
+    return err
+  }
+
+  select {
+    case <-ge.clock.After(startat.Sub(ge.clock.Now())):
+    case <-ctx.Done():
+      return ctx.Err()
+  }
+
+  ge.CurrentGameState.SetStatus(constants.GAME_STATUS_RUNNING)
+  return nil
+}
+
+// StartMatch plays best-of-N rounds of a game mode, stopping early once a team clinches the match
+func (ge *GameEngine) StartMatch(ctx context.Context, mode string, rounds int, roundbreak time.Duration) error {
+  ge.CurrentMatch = NewMatch(mode, rounds, roundbreak, ge.clock)
+  ge.Printf("starting match - %s", ge.CurrentMatch)
+
+  for round := 1; round <= rounds; round++ {
+    if err := ge.NewGame(mode, nil); err != nil {
+      return err
+    }
+
+    // hold on to the round, the engine moves on once it is over
+    gameid := ge.CurrentGame.Id()
+    logname := filepath.Base(ge.Logfile())
+    gamestate := ge.CurrentGameState
+    gameover := ge.gameover
+
+    ge.Printf("starting round %d of %d", round, rounds)
+    if err := ge.StartGame(ctx); err != nil {
+      return err
+    }
+
+    select {
+      case <-gameover:
+      case <-ctx.Done():
+        return ctx.Err()
+    }
+
+    ge.CurrentMatch.AddRound(round, gameid, logname, gamestate)
+    ge.Printf("round %d is over - %s", round, ge.CurrentMatch)
+
+    if team := ge.CurrentMatch.Clinched(); team != "" {
+      ge.Printf("%s has clinched the match", team)
+      break
+    }
+
+    if round < rounds {
+      ge.Printf("next round starts in %s", roundbreak)
+      select {
+        case <-ge.clock.After(roundbreak):
+        case <-ctx.Done():
+          return ctx.Err()
+      }
+    }
+  }
+
+  ge.CurrentMatch.EndMatch()
+  ge.Printf("The match winner is %s (round wins: %v)", strings.Join(ge.CurrentMatch.Winners, constants.COMMA), ge.CurrentMatch.RoundWins)
+
+  return ge.LogMatch()
+}
+
 func (ge *GameEngine) Start(ctx context.Context) error {
   ge.Printf("starting game engine")
 
+  // wakes the loop even when no events come in, so a game with no hits still ends on time
+  ticker := time.NewTicker(1 * time.Second)
+  defer ticker.Stop()
+
   for {
     // we want to eval the game each loop, not just when an event occurs
     if ge.GameInProgress() {
       ge.Printf(ge.CurrentGameState.GameStatus())
-      if ge.CurrentGameState.TimeExpired() {
-        ge.Printf("game time expired, ending game")
-        if err := ge.EndGame(); err != nil {
-          return err
-        }
-      }
 
-      if time.Since(ge.CurrentGameState.Lastcheck) > (10 * time.Second) {
+      if ge.clock.Since(ge.CurrentGameState.Lastcheck) > ge.CurrentGameState.CheckInterval() && !ge.CurrentGameState.TimeExpired() {
         if !ge.CurrentGameState.Checking() {
           ge.Printf("checking on scores")
           ge.CurrentGameState.SetChecking(true)
 
-          scoreboard, nodeboard, err := ge.GetScoreboard()
+          raw, nodeboard, hitboard, err := ge.GetScoreboard()
           if err != nil {
             ge.Printf("error compiling node scores: %s", err)
             return err
           }
+          ge.CurrentGameState.SetRawScoreboard(raw)
+          ge.CurrentGameState.SetHitboard(hitboard)
+          scoreboard := ge.CurrentGameState.config.Handicap(raw)
+
+          // the nodes' own tallies win over anything lost or doubled on the way
+          if drift := ge.CurrentGameState.Drift(scoreboard, nodeboard); len(drift) > 0 {
+            ge.Printf("scores drifted from the node scoreboards: %s", strings.Join(drift, constants.COMMA))
+            ge.CurrentGameState.LogGameEvent(NewGameEvent(constants.SCORE_DRIFT, []byte(strings.Join(drift, constants.COMMA))))
+          }
 
           ge.CurrentGameState.SetBoards(scoreboard, nodeboard)
+          ge.Checkpoint()
         }
       }
 
-      if ge.CurrentGameState.WinningScoreReached() {
-        ge.Printf("the winning score has been reached, ending game")
+      // only end once, even if more than one end condition is met
+      if reason := ge.CurrentGameState.GameOver(); reason != "" {
+        ge.Printf("%s, ending game", reason)
         if err := ge.EndGame(); err != nil {
           return err
         }
@@ -138,14 +356,28 @@ func (ge *GameEngine) Start(ctx context.Context) error {
             ge.Printf("error telling nodes to start game: %s", err)
             return err
           }
+        case constants.GAME_ACTION_PAUSE:
+          ge.Printf("game engine requested pause game - %s", string(evt.Payload))
+          if err := ge.PauseGame(); err != nil {
+            ge.Printf("cannot pause game: %s", err)
+          }
+        case constants.GAME_ACTION_RESUME:
+          ge.Printf("game engine requested resume game - %s", string(evt.Payload))
+          if err := ge.ResumeGame(); err != nil {
+            ge.Printf("cannot resume game: %s", err)
+          }
         case constants.GAME_ACTION_END:
           ge.Printf("game engine requested end game - %s", string(evt.Payload))
-          if err := ge.EndGame(); err != nil {
+          if !ge.GameActive() {
+            ge.Printf("game engine received request when no game in progress")
+            continue
+          }
+          if err := ge.FinishGame(); err != nil {
             return err
           }
         case constants.RANDOM_TEAM_HIT:
           if ge.GameInProgress() {
-            if err := ge.RandomTeamHit(rand.Intn(5)+1); err != nil {
+            if err := ge.RandomTeamHit(ge.CurrentGameState.Rand().Intn(5)+1); err != nil {
               ge.Printf("cannot generate random team hit: %s", err)
             }
           } else {
@@ -159,6 +391,182 @@ func (ge *GameEngine) Start(ctx context.Context) error {
           } else {
             ge.Printf("game engine received request when no game in progress")
           }
+        case constants.RANDOM_HILL:
+          if ge.GameInProgress() {
+            if err := ge.RandomHill(); err != nil {
+              ge.Printf("cannot move the hill: %s", err)
+            }
+          } else {
+            ge.Printf("game engine received request when no game in progress")
+          }
+        case constants.RANDOM_TARGET:
+          if ge.GameInProgress() {
+            if err := ge.RandomTarget(); err != nil {
+              ge.Printf("cannot light random target: %s", err)
+            }
+          } else {
+            ge.Printf("game engine received request when no game in progress")
+          }
+        case constants.SET_TARGET:
+          if !ge.GameInProgress() {
+            ge.Printf("game engine received request when no game in progress")
+            continue
+          }
+
+          node, sensorid, team, err := common.ParseSetTarget(evt.Payload)
+          if err != nil {
+            ge.Printf("error parsing set target: %s", err)
+            continue
+          }
+
+          if err := ge.SetTarget(node, sensorid, team); err != nil {
+            ge.Printf("cannot light target %s:%s: %s", node, sensorid, err)
+          }
+        case constants.TRIAL_START:
+          ge.Printf("%s started the course", string(evt.Payload))
+          ge.CurrentGameState.StartTrial(string(evt.Payload))
+          ge.CurrentGameState.LogGameEvent(evt)
+        case constants.TRIAL_FINISH:
+          trial := ge.CurrentGameState.FinishTrial(string(evt.Payload))
+          if trial == nil {
+            ge.Printf("ignoring finish for %s - not on the course", string(evt.Payload))
+            continue
+          }
+
+          ge.Printf("%s finished the course in %dms (%d misses, %dms penalty)", trial.Team, trial.TimeMs, trial.Misses, trial.PenaltyMs)
+          ge.CurrentGameState.LogGameEvent(evt)
+          ge.Checkpoint()
+        case constants.TARGET_HIT:
+          // reported by the node whose target was hit (see controller HandleEvent)
+          node, sensorid, team, reaction, err := common.ParseTargetHit(evt.Payload)
+          if err != nil {
+            ge.Printf("error parsing target hit: %s", err)
+            continue
+          }
+
+          if !ge.CurrentGameState.HitTarget(node, sensorid, team, reaction) {
+            ge.Printf("ignoring hit on %s:%s - not the current target", node, sensorid)
+            continue
+          }
+
+          ge.Printf("target %s:%s hit by %s in %dms", node, sensorid, team, reaction)
+          if err := ge.SendEventToGame(evt); err != nil {
+            ge.Printf("error sending target hit to game: %s", err)
+          }
+        case constants.SELFTEST_RESULT:
+          node, sensors, err := common.ParseSelfTestResult(evt.Payload)
+          if err != nil {
+            ge.Printf("error parsing self test result: %s", err)
+            continue
+          }
+
+          for id, passed := range sensors {
+            if !passed {
+              ge.Printf("%s: sensor %s failed its self test", node, id)
+            }
+          }
+          ge.AddSelfTest(&SelfTestResult{Node: node, Sensors: sensors, At: ge.clock.Now()})
+        case constants.FALSE_START:
+          // reported by a node hit before the countdown was over, the hit was not counted
+          if ge.CurrentGame == nil {
+            continue
+          }
+          ge.Printf("false start on %s", string(evt.Payload))
+          ge.CurrentGameState.LogGameEvent(evt)
+        case constants.HIT_REPORT:
+          // pushed by the node as each hit is recorded (see controller HandleEvent), only hits the engine
+          // took (or already has) are answered with no error, so the node keeps sending the rest
+          if !ge.GameInProgress() {
+            ge.Printf("game engine received request when no game in progress")
+            evt.Answer(constants.ERR_NO_GAME_RUNNING)
+            continue
+          }
+
+          node, gameid, run, seq, sensorid, team, hits, points, err := common.ParseHitReport(evt.Payload)
+          if err != nil {
+            ge.Printf("error parsing hit report: %s", err)
+            evt.Answer(err)
+            continue
+          }
+
+          if gameid != ge.CurrentGame.Id() {
+            ge.Printf("ignoring hit on %s:%s for game %s", node, sensorid, gameid)
+            evt.Answer(nil) // it will never count, no use sending it again
+            continue
+          }
+
+          if !ge.CurrentGameState.AddHit(node, run, seq, team, hits, points) {
+            ge.Printf("ignoring hit %d on %s:%s - already counted", seq, node, sensorid)
+            evt.Answer(nil)
+            continue
+          }
+          evt.Answer(nil)
+
+          if streaks := ge.CurrentGameState.AddStreak(team, hits, points); streaks > 0 {
+            bonus := streaks * ge.CurrentGameState.config.StreakBonus
+            ge.Printf("%s is on a streak of %d hits (+%d)", team, ge.CurrentGameState.Streak.Hits, bonus)
+            ge.CurrentGameState.AddBonus(team, bonus)
+            pay := strings.Join([]string{team, fmt.Sprintf("%d", ge.CurrentGameState.Streak.Hits), fmt.Sprintf("%d", bonus)}, constants.SPLIT)
+            ge.CurrentGameState.LogGameEvent(NewGameEvent(constants.TEAM_STREAK, []byte(pay)))
+          }
+        case constants.TARGET_MISS:
+          node, sensorid, err := common.ParseTargetMiss(evt.Payload)
+          if err != nil {
+            ge.Printf("error parsing target miss: %s", err)
+            continue
+          }
+
+          ge.Printf("missed target - hit %s:%s instead", node, sensorid)
+          ge.CurrentGameState.MissTarget()
+          ge.CurrentGameState.LogGameEvent(evt)
+        case constants.TARGET_DAMAGE:
+          // reported by the node whose target was hit (see controller HandleEvent)
+          node, sensorid, team, err := common.ParseTargetDamage(evt.Payload)
+          if err != nil {
+            ge.Printf("error parsing target damage: %s", err)
+            continue
+          }
+
+          target := ge.CurrentGameState.DamageTarget(node, sensorid, team)
+          if target == nil {
+            ge.Printf("ignoring damage to %s:%s - not a live target", node, sensorid)
+            continue
+          }
+
+          if target.Eliminated {
+            if err := ge.EliminateTarget(target); err != nil {
+              ge.Printf("error eliminating target %s:%s: %s", node, sensorid, err)
+            }
+          }
+        case constants.TARGET_CAPTURE:
+          // reported by the node whose sensor switched teams (see controller HandleEvent)
+          node, sensorid, team, err := common.ParseTargetCapture(evt.Payload)
+          if err != nil {
+            ge.Printf("error parsing target capture: %s", err)
+            continue
+          }
+
+          if !slices.Contains(ge.CurrentGameState.Teams, team) {
+            ge.Printf("ignoring capture of %s:%s by unknown team %s", node, sensorid, team)
+            continue
+          }
+
+          ge.Printf("%s captured %s:%s", team, node, sensorid)
+          ge.CurrentGameState.CaptureTarget(node, sensorid, team)
+          ge.CurrentGameState.LogGameEvent(evt)
+          ge.Checkpoint()
+        case constants.TERRITORY_TICK:
+          if ge.GameInProgress() {
+            ge.CurrentGameState.AccrueHoldPoints()
+          }
+        case constants.RANDOM_SENSOR_COLORS:
+          if ge.GameInProgress() {
+            if err := ge.RandomSensorColors(); err != nil {
+              ge.Printf("cannot generate random sensor colors: %s", err)
+            }
+          } else {
+            ge.Printf("game engine received request when no game in progress")
+          }
         case constants.RANDOM_SENSOR_HIT:
           if ge.GameInProgress() {
             if err := ge.RandomSensorHit(1); err != nil {
@@ -170,6 +578,7 @@ func (ge *GameEngine) Start(ctx context.Context) error {
         default:
           ge.Printf("Unsupported game event request: %s", evt.Event)
       }
+    case <-ticker.C:
     case <-ctx.Done():
       ge.Printf("stopping game engine")
       return ctx.Err()
@@ -181,7 +590,7 @@ func (ge *GameEngine) WaitForNodes(expect, timeout int) error {
   ge.Printf("waiting for nodes to be ready")
   for {
     // wait for ready
-    resp, err := ge.SendQueryToNodes(NewGameQuery(constants.NODE_READY, []byte(""), constants.NODE_TAGS))
+    resp, err := ge.SendQueryToNodes(NewGameQuery(constants.NODE_READY, []byte(""), ge.NodeTags()))
     if err != nil {
       ge.Printf("error query readiness of nodes: %s", err)
       return err
@@ -206,6 +615,43 @@ func (ge *GameEngine) WaitForNodes(expect, timeout int) error {
   return nil
 }
 
+// ResetNodes zeroes the hits on every node for the mounted game, waiting until they all confirm,
+// when resuming, nodes already counting for the game keep their hits
+func (ge *GameEngine) ResetNodes(expect int, resume bool) error {
+  gameid := ge.CurrentGame.Id()
+  ge.Printf("resetting nodes for game %s (seed %d)", gameid, ge.CurrentGameState.Seed)
+
+  // nodes seed their own randomness (i.e. random sensors) from the game seed
+  parts := []string{gameid, fmt.Sprintf("%d", ge.CurrentGameState.Seed)}
+  if resume {
+    parts = append(parts, constants.GAME_RESUME_ID)
+  }
+  pay := strings.Join(parts, constants.SPLIT)
+
+  for {
+    resp, err := ge.SendQueryToNodes(NewGameQuery(constants.GAME_ACTION_RESET, []byte(pay), ge.NodeTags()))
+    if err != nil {
+      ge.Printf("error resetting nodes: %s", err)
+      return err
+    }
+
+    resetcount := 0
+    for _, val := range resp {
+      if string(val) == gameid {
+        resetcount += 1
+      }
+    }
+
+    if resetcount >= expect {
+      ge.Printf("nodes reset: %d", resetcount)
+      return nil
+    }
+
+    ge.Printf("waiting for %d nodes to reset [%d/%d]...", expect, resetcount, expect)
+    time.Sleep(time.Duration(ge.conf.Timeout) * time.Second)
+  }
+}
+
 func (ge *GameEngine) WaitForGameModeSetup(mode string) error {
   ge.Printf("waiting for all game nodes to have proper configuration")
 
@@ -216,7 +662,7 @@ func (ge *GameEngine) WaitForGameModeSetup(mode string) error {
   }
 
   // query all nodes game mode
-  resp, err := ge.SendQueryToNodes(NewGameQuery(constants.GAME_MODE, []byte(""), constants.NODE_TAGS))
+  resp, err := ge.SendQueryToNodes(NewGameQuery(constants.GAME_MODE, []byte(""), ge.NodeTags()))
   if err != nil {
     ge.Printf("error querying game node: %s", err)
     return err
@@ -246,7 +692,24 @@ func (ge *GameEngine) WaitForGameModeSetup(mode string) error {
   return nil
 }
 
+// SendRulesToNodes tells each node how its hits are scored and which sensors the game lights
+func (ge *GameEngine) SendRulesToNodes() error {
+  for _, node := range ge.CurrentGameState.Nodes {
+    data, err := json.Marshal(NewNodeRulesFor(node, ge.CurrentGameState.config))
+    if err != nil {
+      return err
+    }
+
+    evt := strings.Join([]string{node, constants.GAME_RULES}, constants.SPLIT)
+    if err := ge.SendEventToNodes(NewGameEvent(evt, data)); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
 func (ge *GameEngine) FailGame(err error) error {
+  defer ge.CloseGameOver()
   ge.CurrentGameState.SetStatus(constants.GAME_STATUS_FAILED)
   ge.CurrentGameState.LogGameEvent(NewGameEvent(constants.GAME_ERROR, []byte(fmt.Sprintf("%s", err))))
 
@@ -254,13 +717,105 @@ func (ge *GameEngine) FailGame(err error) error {
     return err
   }
 
+  ge.RemoveCheckpoint()
+  ge.lastended = ge.clock.Now()
   ge.CurrentGame = nil
   ge.CurrentGameState = NewGameState(NewGameConfig(ge.conf))
   return nil
 }
 
+// EndGame ends the game, unless it ended level in regulation and the tie break policy plays on
 func (ge *GameEngine) EndGame() error {
-  ge.CurrentGameState.EndGame()
+  tiebreak := ge.CurrentGameState.config.TieBreak
+  if !ge.CurrentGameState.InRegulation() || ge.CurrentGameState.TimeTrial() || tiebreak == constants.TIE_BREAK_DRAW || tiebreak == "" {
+    return ge.FinishGame()
+  }
+
+  raw, nodeboard, hitboard, err := ge.GetScoreboard()
+  if err != nil {
+    ge.Printf("error compiling node scores: %s", err)
+    return err
+  }
+  ge.CurrentGameState.SetRawScoreboard(raw)
+  ge.CurrentGameState.SetHitboard(hitboard)
+  scoreboard := ge.CurrentGameState.config.Handicap(raw)
+
+  ge.CurrentGameState.SetBoards(scoreboard, nodeboard)
+  leaders, highscore := ge.CurrentGameState.Leaders()
+  if len(leaders) < 2 {
+    return ge.FinishGame()
+  }
+
+  return ge.StartTieBreak(tiebreak, leaders, highscore)
+}
+
+// StartTieBreak plays on in overtime or sudden death and tells the nodes about the new phase
+func (ge *GameEngine) StartTieBreak(phase string, tied []string, score int) error {
+  extra, err := time.ParseDuration(ge.CurrentGameState.config.Overtime)
+  if err != nil || extra <= 0 {
+    ge.Printf("invalid overtime '%s', using %s", ge.CurrentGameState.config.Overtime, constants.DEFAULT_OVERTIME)
+    extra = constants.DEFAULT_OVERTIME
+  }
+
+  ge.Printf("%s tied at %d - going to %s for up to %s", strings.Join(tied, constants.COMMA), score, phase, extra)
+  ge.CurrentGameState.StartTieBreak(phase, tied, score, extra)
+
+  evt := NewGameEvent(constants.GAME_PHASE, []byte(phase))
+  ge.CurrentGameState.LogGameEvent(evt)
+  ge.Checkpoint()
+  if err := ge.SendEventToNodes(evt); err != nil {
+    ge.Printf("error sending game phase: %s", err)
+    return err
+  }
+
+  return nil
+}
+
+// PauseGame freezes the game clock and tells the nodes to stop taking hits
+func (ge *GameEngine) PauseGame() error {
+  if ge.CurrentGame == nil {
+    return constants.ERR_NO_GAME_RUNNING
+  }
+
+  if err := ge.CurrentGameState.Pause(); err != nil {
+    return err
+  }
+
+  evt := NewGameEvent(constants.GAME_ACTION_PAUSE, []byte(fmt.Sprintf("paused at %s", ge.CurrentGameState.PausedAt.Format(time.RFC3339))))
+  ge.SendEventToGame(evt) // logs the event, and the game holds its timers while paused
+  ge.Checkpoint()
+  return ge.SendEventToNodes(evt)
+}
+
+// ResumeGame picks the game up where it was paused
+func (ge *GameEngine) ResumeGame() error {
+  if ge.CurrentGame == nil {
+    return constants.ERR_NO_GAME_RUNNING
+  }
+
+  paused, err := ge.CurrentGameState.Resume()
+  if err != nil {
+    return err
+  }
+
+  evt := NewGameEvent(constants.GAME_ACTION_RESUME, []byte(fmt.Sprintf("resumed after %s", paused.Round(time.Second))))
+  ge.SendEventToGame(evt) // logs the event, and the game starts its timers again
+  ge.Checkpoint()
+  return ge.SendEventToNodes(evt)
+}
+
+// FinishGame ends the game for good, declaring a draw if the leaders are still level
+func (ge *GameEngine) FinishGame() error {
+  if ge.CurrentGame == nil {
+    return constants.ERR_NO_GAME_RUNNING
+  }
+
+  // the first tied team to score wins sudden death, even if another catches up before the final count
+  suddendeath := ge.CurrentGameState.SuddenDeathWinner()
+  if !ge.CurrentGameState.EndGame() {
+    return constants.ERR_NO_GAME_RUNNING // already ended
+  }
+  defer ge.CloseGameOver()
 
   if err := ge.SendEventToGame(NewGameEvent(constants.GAME_ACTION_OFF, []byte("turn off game"))); err != nil {
     ge.Printf("error shutting down game: %s", err)
@@ -272,32 +827,57 @@ func (ge *GameEngine) EndGame() error {
     return err
   }
 
-  scoreboard, nodeboard, err := ge.GetScoreboard()
+  raw, nodeboard, hitboard, err := ge.GetScoreboard()
   if err != nil {
     ge.Printf("error compiling node scores: %s", err)
     return err
   }
+  ge.CurrentGameState.SetRawScoreboard(raw)
+  ge.CurrentGameState.SetHitboard(hitboard)
+  scoreboard := ge.CurrentGameState.config.Handicap(raw)
 
   ge.CurrentGameState.SetBoards(scoreboard, nodeboard)
-  ge.Printf("Final Score: %s", scoreboard)
+  ge.Printf("Final Score: %v", scoreboard)
 
-  for team, count := range scoreboard {
-    if count > ge.CurrentGameState.Highscore {
-      ge.CurrentGameState.SetWinner(team, count)
-    }
+  leaders, highscore := ge.CurrentGameState.Leaders()
+  if suddendeath != "" {
+    leaders = []string{suddendeath}
+    highscore = scoreboard[suddendeath]
   }
 
-  ge.Printf("The winning team is %s with a score of %d", ge.CurrentGameState.Winner, ge.CurrentGameState.Highscore)
+  if ge.CurrentGameState.TimeTrial() {
+    // the fastest time wins, not the most hits
+    fastest, best := ge.CurrentGameState.Fastest()
+    leaders, highscore = fastest, 0
+    ge.CurrentGameState.SetBestTime(best)
+    ge.Printf("The best time is %dms", best)
+  }
+  ge.CurrentGameState.SetWinners(leaders, highscore)
 
-  if err := ge.SendEventToNodes(NewGameEvent(constants.GAME_WINNER, []byte(ge.CurrentGameState.Winner))); err != nil {
-    log.Printf("error sending team winner: %s", err)
-    return err
+  if len(leaders) > 1 {
+    ge.Printf("The game is a draw between %s with a score of %d", ge.CurrentGameState.Winner, highscore)
+
+    evt := NewGameEvent(constants.GAME_PHASE, []byte(constants.GAME_PHASE_DRAW))
+    ge.CurrentGameState.LogGameEvent(evt)
+    if err := ge.SendEventToNodes(evt); err != nil {
+      log.Printf("error sending game draw: %s", err)
+      return err
+    }
+  } else {
+    ge.Printf("The winning team is %s with a score of %d", ge.CurrentGameState.Winner, ge.CurrentGameState.Highscore)
+
+    if err := ge.SendEventToNodes(NewGameEvent(constants.GAME_WINNER, []byte(ge.CurrentGameState.Winner))); err != nil {
+      log.Printf("error sending team winner: %s", err)
+      return err
+    }
   }
 
   if err := ge.LogGame(); err != nil {
     return err
   }
 
+  ge.RemoveCheckpoint()
+  ge.lastended = ge.clock.Now()
   return nil
 }
 
@@ -314,16 +894,19 @@ func (ge *GameEngine) LogGame() error {
   return nil
 }
 
-func (ge *GameEngine) GetScoreboard() (map[string]int, map[string]int, error) {
+// GetScoreboard polls the nodes for the raw team scores, node scores and team hits, before any
+// handicap (see GameConfig.Handicap) or bonus, callers set them on the game state
+func (ge *GameEngine) GetScoreboard() (map[string]int, map[string]int, map[string]int, error) {
   scoreboard := map[string]int{}
   nodeboard := map[string]int{}
+  hitboard := map[string]int{}
   nodes := ge.CurrentGameState.Nodes
   teams := ge.CurrentGameState.Teams
 
-  resp, err := ge.SendQueryToNodes(NewGameQuery(constants.NODE_SCOREBOARD, []byte(""), constants.NODE_TAGS))
+  resp, err := ge.SendQueryToNodes(NewGameQuery(constants.NODE_SCOREBOARD, []byte(ge.CurrentGame.Id()), ge.NodeTags()))
   if err != nil {
     ge.Printf("error querying node scoreboards: %s", err)
-    return scoreboard, nodeboard, err
+    return scoreboard, nodeboard, hitboard, err
   }
 
   // accumulate each node response
@@ -334,6 +917,13 @@ func (ge *GameEngine) GetScoreboard() (map[string]int, map[string]int, error) {
       ge.Printf("cannot parse node hits: %s", err)
     } else {
       for key,count := range nodehits {
+        if team, ok := strings.CutPrefix(key, constants.HITS_PREFIX); ok {
+          if slices.Contains(teams, team) {
+            hitboard[team] += count
+          }
+          continue
+        }
+
         isnode := slices.Contains(nodes, key)
         isteam := slices.Contains(teams, key)
 
@@ -361,18 +951,28 @@ func (ge *GameEngine) GetScoreboard() (map[string]int, map[string]int, error) {
     }
   }
 
-  return scoreboard, nodeboard, nil
-}
+  // handicapped teams start with their bonus even before they have a hit
+  for team := range ge.CurrentGameState.config.Handicaps {
+    if _, ok := scoreboard[team]; !ok && slices.Contains(teams, team) {
+      scoreboard[team] = 0
+    }
+  }
 
+  return scoreboard, nodeboard, hitboard, nil
+}
 
+// SendEventToGame hands an event to the game, waiting a while if it is busy since games only move on
+// the events they get (i.e. a time trial waits for each target hit), it is dropped if the game never takes it
 func (ge *GameEngine) SendEventToGame(e GameEvent) error {
   select {
     case ge.gamechan.GameChan <- e:
       ge.CurrentGameState.LogGameEvent(e)
-    default:
+      return nil
+    case <-time.After(constants.GAME_CHAN_TIMEOUT): // real time, the game is busy whatever the game clock says
       ge.Printf("game chan is full - discarding event: %s", e)
+      ge.Dropped.Add(constants.DROPPED_GAME_CHAN)
+      return constants.ERR_GAME_CHAN_FULL
   }
-  return nil
 }
 
 func (ge *GameEngine) SendEventToNodes(e GameEvent) error {
@@ -387,8 +987,8 @@ func (ge *GameEngine) SendQueryToNodes(q GameQuery) (map[string][]byte, error) {
 }
 
 func (ge *GameEngine) RandomTeamHits() error {
-  for i := 1; i <= rand.Intn(10); i++ {
-    if err := ge.RandomTeamHit(rand.Intn(5) + 1); err != nil {
+  for i := 1; i <= ge.CurrentGameState.Rand().Intn(10); i++ {
+    if err := ge.RandomTeamHit(ge.CurrentGameState.Rand().Intn(5) + 1); err != nil {
       return err
     }
   }
@@ -423,13 +1023,118 @@ func (ge *GameEngine) RandomSensorHit(hits int) error {
 
 func (ge *GameEngine) RandomSensorColor() error {
   node := ge.CurrentGameState.RandomNode()
-  sensorid := constants.RANDOM_SENSOR_ID
+  return ge.SensorColor(node, constants.RANDOM_SENSOR_ID, constants.RANDOM_COLOR_ID)
+}
+
+// SensorColor asks a node to set the color of one (or all) of its sensors
+func (ge *GameEngine) SensorColor(node, sensorid, color string) error {
   evt := strings.Join([]string{node, constants.SENSOR_COLOR_REQUEST}, constants.SPLIT)
-  pay := strings.Join([]string{sensorid, constants.RANDOM_COLOR_ID}, constants.SPLIT)
+  pay := strings.Join([]string{sensorid, color}, constants.SPLIT)
   if err := ge.SendEventToNodes(NewGameEvent(evt, []byte(pay))); err != nil {
-    ge.Printf("error sending random sensor color %s: %s", sensorid, err)
+    ge.Printf("error sending sensor color %s to %s: %s", color, node, err)
     return err
   }
 
   return nil
 }
+
+// RandomHill moves the hill to another random node, then lights every sensor on it
+func (ge *GameEngine) RandomHill() error {
+  hill := ge.CurrentGameState.RandomNode()
+  for len(ge.CurrentGameState.Nodes) > 1 && hill == ge.CurrentGameState.Hill {
+    hill = ge.CurrentGameState.RandomNode()
+  }
+
+  ge.Printf("the hill is now %s", hill)
+  ge.CurrentGameState.SetHill(hill)
+
+  evt := NewGameEvent(constants.GAME_HILL, []byte(hill))
+  ge.CurrentGameState.LogGameEvent(evt)
+  if err := ge.SendEventToNodes(evt); err != nil {
+    ge.Printf("error sending hill %s to nodes: %s", hill, err)
+    return err
+  }
+
+  return ge.SensorColor(hill, constants.ALL_SENSOR_ID, constants.RANDOM_COLOR_ID)
+}
+
+// RandomTarget turns off the current target and lights a single sensor on a random node
+// in a random team's color
+func (ge *GameEngine) RandomTarget() error {
+  team := ge.CurrentGameState.config.TargetColor
+  if team == "" {
+    team = ge.CurrentGameState.RandomTeam()
+  }
+
+  return ge.SetTarget(ge.CurrentGameState.RandomNode(), constants.RANDOM_SENSOR_ID, team)
+}
+
+// SetTarget lights a single target sensor (which can be rand) on a node, clearing the last target
+func (ge *GameEngine) SetTarget(node, sensorid, team string) error {
+  // a node replaces its own target, so only clear the old one if it is elsewhere
+  if ge.CurrentGameState.Target != nil && ge.CurrentGameState.Target.Node != node {
+    prev := ge.CurrentGameState.Target.Node
+    evt := strings.Join([]string{prev, constants.TARGET_CLEAR}, constants.SPLIT)
+    if err := ge.SendEventToNodes(NewGameEvent(evt, []byte("target moved"))); err != nil {
+      ge.Printf("error clearing target on %s: %s", prev, err)
+      return err
+    }
+  }
+
+  ge.CurrentGameState.LightTarget(node, sensorid, team)
+
+  evt := strings.Join([]string{node, constants.TARGET_REQUEST}, constants.SPLIT)
+  pay := strings.Join([]string{sensorid, team}, constants.SPLIT)
+  if err := ge.SendEventToNodes(NewGameEvent(evt, []byte(pay))); err != nil {
+    ge.Printf("error sending target to %s: %s", node, err)
+    return err
+  }
+
+  return nil
+}
+
+// RandomSensorColors lights every sensor on every node in a random color
+func (ge *GameEngine) RandomSensorColors() error {
+  for _, node := range ge.CurrentGameState.Nodes {
+    if err := ge.SensorColor(node, constants.ALL_SENSOR_ID, constants.RANDOM_COLOR_ID); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// SetupTargets gives every sensor on every node full health
+func (ge *GameEngine) SetupTargets() error {
+  resp, err := ge.SendQueryToNodes(NewGameQuery(constants.NODE_SENSORS, []byte(""), ge.NodeTags()))
+  if err != nil {
+    ge.Printf("error querying node sensors: %s", err)
+    return err
+  }
+
+  for node, val := range resp {
+    if len(val) == 0 {
+      continue
+    }
+    for _, sensorid := range strings.Split(string(val), constants.COMMA) {
+      ge.CurrentGameState.AddTarget(node, sensorid)
+    }
+  }
+
+  ge.Printf("%d targets with %d health each", len(ge.CurrentGameState.Targets), ge.CurrentGameState.config.TargetHealth)
+  return nil
+}
+
+// EliminateTarget turns off a target on its node and awards the capture bonus to the final blow
+func (ge *GameEngine) EliminateTarget(target *TargetState) error {
+  ge.Printf("target %s:%s eliminated by %s", target.Node, target.Sensor, target.EliminatedBy)
+
+  if slices.Contains(ge.CurrentGameState.Teams, target.EliminatedBy) {
+    ge.CurrentGameState.AddBonus(target.EliminatedBy, ge.CurrentGameState.config.CaptureBonus)
+  }
+
+  ge.CurrentGameState.LogGameEvent(NewGameEvent(constants.TARGET_DOWN, []byte(strings.Join([]string{target.Node, target.Sensor, target.EliminatedBy}, constants.SPLIT))))
+  ge.Checkpoint()
+
+  evt := strings.Join([]string{target.Node, constants.TARGET_DOWN}, constants.SPLIT)
+  return ge.SendEventToNodes(NewGameEvent(evt, []byte(target.Sensor)))
+}
@@ -0,0 +1,213 @@
+package game
+
+import (
+  "fmt"
+  "log"
+  "time"
+  "context"
+  "testing"
+  "github.com/taemon1337/arena-nerf/pkg/common"
+  "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+func newTestGameEngine() *GameEngine {
+  cfg := config.NewConfig(log.Default())
+  cfg.Nodes = []string{"node1", "node2", "node3"}
+  cfg.Colors = []string{"red", "blue"}
+  cfg.Teams = []string{"red", "blue"}
+  return NewGameEngine(cfg, NewGameChannel(), log.Default())
+}
+
+func TestCloseGameOver(t *testing.T) {
+  ge := newTestGameEngine()
+  if err := ge.NewGame(constants.GAME_MODE_DRILL, nil); err != nil {
+    t.Fatalf("cannot mount game: %s", err)
+  }
+  gameover := ge.gameover
+
+  // i.e. a failed game closing it, then a late finish
+  ge.CloseGameOver()
+  ge.CloseGameOver()
+
+  select {
+    case <-gameover:
+    default:
+      t.Fatalf("game over not closed")
+  }
+
+  // the next game gets its own
+  if err := ge.NewGame(constants.GAME_MODE_DRILL, nil); err != nil {
+    t.Fatalf("cannot mount game: %s", err)
+  }
+  select {
+    case <-ge.gameover:
+      t.Fatalf("next game over already closed")
+    default:
+  }
+}
+
+func TestSendEventToGame(t *testing.T) {
+  ge := newTestGameEngine()
+  if err := ge.NewGame(constants.GAME_MODE_DRILL, nil); err != nil {
+    t.Fatalf("cannot mount game: %s", err)
+  }
+
+  timeout := constants.GAME_CHAN_TIMEOUT
+  constants.GAME_CHAN_TIMEOUT = 50 * time.Millisecond
+  defer func() { constants.GAME_CHAN_TIMEOUT = timeout }()
+
+  for i := 0; i < constants.CHANNEL_WIDTH; i++ {
+    if err := ge.SendEventToGame(NewGameEvent(constants.TARGET_HIT, []byte("queued"))); err != nil {
+      t.Fatalf("cannot queue event %d: %s", i, err)
+    }
+  }
+
+  // a busy game that gets to its events in time gets them all
+  go func() {
+    time.Sleep(10 * time.Millisecond)
+    <-ge.gamechan.GameChan
+  }()
+  if err := ge.SendEventToGame(NewGameEvent(constants.TARGET_HIT, []byte("waited"))); err != nil {
+    t.Fatalf("event dropped while the game was busy: %s", err)
+  }
+
+  // one that never does, drops them
+  if err := ge.SendEventToGame(NewGameEvent(constants.TARGET_HIT, []byte("dropped"))); err != constants.ERR_GAME_CHAN_FULL {
+    t.Fatalf("got %v sending to a stuck game, want %s", err, constants.ERR_GAME_CHAN_FULL)
+  }
+  if dropped := ge.Dropped.Counts()[constants.DROPPED_GAME_CHAN]; dropped != 1 {
+    t.Fatalf("counted %d dropped events, want 1", dropped)
+  }
+}
+
+// answerNodes plays the nodes for the engine, answering every query with the answers given for it
+// (nothing by default) and taking every node event, until the test is over
+func answerNodes(t *testing.T, ge *GameEngine, answers map[string]map[string][]byte) {
+  ctx, cancel := context.WithCancel(context.Background())
+  t.Cleanup(cancel)
+  go func() {
+    for {
+      select {
+        case q := <-ge.gamechan.QueryChan:
+          answer, ok := answers[q.Query]
+          if !ok {
+            answer = map[string][]byte{}
+          }
+          q.Response <- NewGameQueryResponse(answer, nil)
+        case <-ge.gamechan.NodeChan:
+        case <-ctx.Done():
+          return
+      }
+    }
+  }()
+}
+
+// reportHit sends a hit report to a running engine the way the controller does, returning its answer
+func reportHit(t *testing.T, ge *GameEngine, payload string) error {
+  evt := NewGameEvent(constants.HIT_REPORT, []byte(payload))
+  evt.Reply = make(chan error, 1)
+  ge.gamechan.RequestChan <- evt
+  select {
+    case err := <-evt.Reply:
+      return err
+    case <-time.After(time.Second):
+      t.Fatalf("no answer to hit report %s", payload)
+      return nil
+  }
+}
+
+func TestHitReportBeforeGame(t *testing.T) {
+  ge := newTestGameEngine()
+  answerNodes(t, ge, nil)
+
+  ctx, cancel := context.WithCancel(context.Background())
+  defer cancel()
+  go ge.Start(ctx)
+
+  // no ack before the game, so the node sends it again once the game is on
+  if err := reportHit(t, ge, "node1:game:run1:1:one:red:1:1"); err != constants.ERR_NO_GAME_RUNNING {
+    t.Fatalf("got %v for a hit before the game, want %s", err, constants.ERR_NO_GAME_RUNNING)
+  }
+}
+
+func TestHitReportAnswer(t *testing.T) {
+  ge := newTestGameEngine()
+  answerNodes(t, ge, nil)
+
+  if err := ge.NewGame(constants.GAME_MODE_DRILL, nil); err != nil {
+    t.Fatalf("cannot mount game: %s", err)
+  }
+  if err := ge.CurrentGameState.GameSetup(); err != nil {
+    t.Fatalf("cannot set game up: %s", err)
+  }
+  gameid := ge.CurrentGame.Id()
+
+  ctx, cancel := context.WithCancel(context.Background())
+  defer cancel()
+  go ge.Start(ctx)
+
+  tests := []struct {
+    name      string
+    payload   string
+    ack       bool
+  }{
+    {"new hit", "node1:" + gameid + ":run1:1:one:red:1:1", true},
+    {"same hit again", "node1:" + gameid + ":run1:1:one:red:1:1", true},
+    {"another game", "node1:old-game:run1:2:one:red:1:1", true},
+    {"bad payload", "node1:" + gameid + ":run1", false},
+  }
+
+  for _, tt := range tests {
+    err := reportHit(t, ge, tt.payload)
+    if tt.ack && err != nil {
+      t.Errorf("%s: not acked - %s", tt.name, err)
+    }
+    if !tt.ack && err == nil {
+      t.Errorf("%s: acked", tt.name)
+    }
+  }
+}
+
+func TestCountdown(t *testing.T) {
+  clock := common.NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+  ge := newTestGameEngine()
+  ge.conf.Countdown = 5
+  ge.SetClock(clock)
+  if err := ge.NewGame(constants.GAME_MODE_DRILL, nil); err != nil {
+    t.Fatalf("cannot mount game: %s", err)
+  }
+
+  done := make(chan error, 1)
+  go func() { done <- ge.Countdown(context.Background()) }()
+
+  // every node is told the same start time, however late the event reaches it
+  evt := <-ge.gamechan.NodeChan
+  startat := clock.Now().Add(5 * time.Second)
+  if evt.Event != constants.GAME_ACTION_COUNTDOWN || string(evt.Payload) != fmt.Sprintf("%d", startat.UnixMilli()) {
+    t.Fatalf("sent %s, want a countdown to %d", evt, startat.UnixMilli())
+  }
+
+  clock.Advance(4 * time.Second)
+  select {
+    case <-done:
+      t.Fatalf("countdown over 1s early")
+    case <-time.After(10 * time.Millisecond):
+  }
+  if ge.CurrentGameState.Status != constants.GAME_STATUS_STARTING {
+    t.Fatalf("game %s during the countdown", ge.CurrentGameState.Status)
+  }
+
+  clock.Advance(1 * time.Second)
+  select {
+    case err := <-done:
+      if err != nil {
+        t.Fatalf("countdown failed: %s", err)
+      }
+    case <-time.After(time.Second):
+      t.Fatalf("countdown not over on time")
+  }
+  if ge.CurrentGameState.Status != constants.GAME_STATUS_RUNNING || !ge.CurrentGameState.StartedAt.Equal(startat) {
+    t.Fatalf("game %s from %s, want running from %s", ge.CurrentGameState.Status, ge.CurrentGameState.StartedAt, startat)
+  }
+}
@@ -1,8 +1,13 @@
 package game
 
+import (
+  "fmt"
+)
+
 type GameEvent struct {
   Event         string        `yaml:"event" json:"event"`
   Payload       []byte        `yaml:"payload" json:"payload"`
+  Reply         chan error    `yaml:"-" json:"-"` // set when the sender waits to hear whether the engine took the event
 }
 
 type GameQueryResponse struct {
@@ -14,6 +19,7 @@ type GameQuery struct {
   Query           string                  `yaml:"query" json:"query"`
   Payload         []byte                  `yaml:"payload" json:"payload"`
   Tags            map[string]string       `yaml:"tags" json:"tags"`
+  Nodes           []string                `yaml:"nodes" json:"nodes"` // only ask these nodes (and stop waiting once they answer), all when empty
   Response        chan GameQueryResponse  `yaml:"-" json:"-"`
 }
 
@@ -29,11 +35,24 @@ func NewGameEvent(name string, payload []byte) GameEvent {
   }
 }
 
+// String prints the event as it always has in the logs, leaving out the reply
+func (e GameEvent) String() string {
+  return fmt.Sprintf("{%s %s}", e.Event, e.Payload)
+}
+
+// Answer tells the sender (if it waits) whether the engine took the event
+func (e GameEvent) Answer(err error) {
+  if e.Reply != nil {
+    e.Reply <- err
+  }
+}
+
 func NewGameQuery(query string, payload []byte, tags map[string]string) GameQuery {
   return GameQuery{
     Query:     query,
     Payload:   payload,
     Tags:      tags,
+    Nodes:     []string{},
     Response:  make(chan GameQueryResponse, 0),
   }
 }
@@ -4,6 +4,7 @@ import (
   "log"
   "context"
   "github.com/google/uuid"
+  "github.com/taemon1337/arena-nerf/pkg/common"
   "github.com/taemon1337/arena-nerf/pkg/config"
   "github.com/taemon1337/arena-nerf/pkg/constants"
 )
@@ -16,12 +17,51 @@ type Game interface {
   String() string
 }
 
+// GameConfigurer is implemented by games that change the game config when mounted
+type GameConfigurer interface {
+  Configure(gc *GameConfig)
+}
+
+// GameClock is implemented by games with timers, so they run on the engine's clock
+type GameClock interface {
+  SetClock(clock common.Clock)
+}
+
 func NewGame(mode string, cfg *config.Config, gc *GameChannel, logger *log.Logger) Game {
-  id := uuid.New().String()
+  return NewGameWithId(uuid.New().String(), mode, cfg, gc, logger)
+}
+
+// IsBuiltInMode is true for the game modes NewGameWithId knows, which yaml game modes cannot replace
+func IsBuiltInMode(mode string) bool {
+  switch mode {
+    case constants.GAME_MODE_SIMULATION, constants.GAME_MODE_KING_OF_THE_HILL, constants.GAME_MODE_WHACK_A_MOLE,
+      constants.GAME_MODE_ELIMINATION, constants.GAME_MODE_TERRITORY, constants.GAME_MODE_DRILL, constants.GAME_MODE_TIME_TRIAL:
+      return true
+  }
+  return false
+}
+
+// NewGameWithId creates a game that already has an id, i.e. one restored from a checkpoint
+func NewGameWithId(id, mode string, cfg *config.Config, gc *GameChannel, logger *log.Logger) Game {
   switch mode {
     case constants.GAME_MODE_SIMULATION:
       return NewGameSimulation(id, mode, cfg, gc, map[string]int{}, logger)
+    case constants.GAME_MODE_KING_OF_THE_HILL:
+      return NewGameKingOfTheHill(id, mode, cfg, gc, logger)
+    case constants.GAME_MODE_WHACK_A_MOLE:
+      return NewGameWhackAMole(id, mode, cfg, gc, logger)
+    case constants.GAME_MODE_ELIMINATION:
+      return NewGameElimination(id, mode, cfg, gc, logger)
+    case constants.GAME_MODE_TERRITORY:
+      return NewGameTerritory(id, mode, cfg, gc, logger)
+    case constants.GAME_MODE_DRILL:
+      return NewGameDrill(id, mode, cfg, gc, logger)
+    case constants.GAME_MODE_TIME_TRIAL:
+      return NewGameTimeTrial(id, mode, cfg, gc, logger)
     default:
+      if rules := GetGameRules(mode); rules != nil {
+        return NewGameRuleset(id, mode, rules, cfg, gc, logger)
+      }
       logger.Printf("Unsupported game mode %s", mode)
       return nil
   }
@@ -0,0 +1,193 @@
+package game
+
+import (
+  "fmt"
+  "time"
+  "strings"
+  "context"
+  "encoding/json"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// SensorHealth is what a sensor's hardware is up to, the json is kept short to fit a serf query response
+type SensorHealth struct {
+  Id            string            `yaml:"id" json:"id"`
+  Hits          bool              `yaml:"hits" json:"hits"` // a hit pin is set and hits are enabled
+  HitLine       bool              `yaml:"hit_line" json:"hit_line"` // the gpio hit line is held
+  Leds          bool              `yaml:"leds" json:"leds"` // an led pin is set and leds are enabled
+  Led           bool              `yaml:"led" json:"led"`
+  LedStrip      bool              `yaml:"led_strip" json:"led_strip"`
+  LastHit       time.Time         `yaml:"last_hit" json:"last_hit"`
+  Dropped       map[string]uint64 `yaml:"dropped,omitempty" json:"dropped,omitempty"` // events lost to a full channel
+}
+
+// NodeHealth is a node's answer to a health query
+type NodeHealth struct {
+  Node          string            `yaml:"node" json:"node"`
+  Version       string            `yaml:"version" json:"version"`
+  Uptime        time.Duration     `yaml:"uptime" json:"uptime"`
+  Status        string            `yaml:"status" json:"status"`
+  Mode          string            `yaml:"mode" json:"mode"`
+  GameId        string            `yaml:"game_id" json:"game_id"`
+  Sensors       []SensorHealth    `yaml:"sensors" json:"sensors"`
+  Dropped       map[string]uint64 `yaml:"dropped,omitempty" json:"dropped,omitempty"` // events the node lost to a full channel
+  CheckedAt     time.Time         `yaml:"checked_at" json:"checked_at"` // when the controller last heard from the node
+  Problems      []string          `yaml:"problems,omitempty" json:"problems,omitempty"` // filled in by the controller
+}
+
+// SelfTestResult is which sensors of a node were hit while it blinked them one by one
+type SelfTestResult struct {
+  Node          string            `yaml:"node" json:"node"`
+  Sensors       map[string]bool   `yaml:"sensors" json:"sensors"` // true for a pass
+  At            time.Time         `yaml:"at" json:"at"`
+}
+
+// Check lists anything wrong with the node that would spoil a game
+func (nh *NodeHealth) Check(now time.Time, stale time.Duration) []string {
+  problems := []string{}
+  if nh.CheckedAt.IsZero() {
+    problems = append(problems, "never answered")
+  } else if now.Sub(nh.CheckedAt) > stale {
+    problems = append(problems, fmt.Sprintf("no answer since %s", nh.CheckedAt.Format(time.RFC3339)))
+  }
+
+  for _, sh := range nh.Sensors {
+    if sh.Hits && !sh.HitLine {
+      problems = append(problems, fmt.Sprintf("sensor %s has no gpio hit line", sh.Id))
+    }
+    if sh.Leds && !sh.Led && !sh.LedStrip {
+      problems = append(problems, fmt.Sprintf("sensor %s has no led connected", sh.Id))
+    }
+  }
+  return problems
+}
+
+// GetNodeHealth asks every node of the arena how it is doing
+func (ge *GameEngine) GetNodeHealth() (map[string]*NodeHealth, error) {
+  health := map[string]*NodeHealth{}
+
+  resp, err := ge.SendQueryToNodes(NewGameQuery(constants.NODE_HEALTH, []byte{}, ge.NodeTags()))
+  if err != nil {
+    return health, err
+  }
+
+  for node, data := range resp {
+    nh := &NodeHealth{}
+    if err := json.Unmarshal(data, nh); err != nil {
+      ge.Printf("cannot parse health of %s: %s", node, err)
+      continue
+    }
+    nh.CheckedAt = ge.clock.Now()
+    health[node] = nh
+  }
+  return health, nil
+}
+
+// MonitorHealth checks on the nodes every interval, keeping the last answer of each so a node that
+// stops answering shows up as stale rather than disappearing
+func (ge *GameEngine) MonitorHealth(ctx context.Context, interval time.Duration) error {
+  if interval <= 0 {
+    return nil
+  }
+
+  ticker := time.NewTicker(interval)
+  defer ticker.Stop()
+
+  for {
+    ge.CheckHealth(interval)
+
+    select {
+      case <-ticker.C:
+      case <-ctx.Done():
+        return ctx.Err()
+    }
+  }
+}
+
+// CheckHealth asks the nodes how they are doing and logs any problems, a node is stale after missing two checks
+func (ge *GameEngine) CheckHealth(interval time.Duration) {
+  health, err := ge.GetNodeHealth()
+  if err != nil {
+    ge.Printf("error checking node health: %s", err)
+    return
+  }
+
+  ge.healthlock.Lock()
+  defer ge.healthlock.Unlock()
+
+  for node, nh := range health {
+    ge.health[node] = nh
+  }
+
+  // the expected nodes show up even if they never answered
+  for _, node := range ge.conf.Nodes {
+    if _, ok := ge.health[node]; !ok {
+      ge.health[node] = &NodeHealth{Node: node, Sensors: []SensorHealth{}}
+    }
+  }
+
+  now := ge.clock.Now()
+  for node, nh := range ge.health {
+    nh.Problems = nh.Check(now, 2 * interval)
+    for _, problem := range nh.Problems {
+      ge.Printf("%s: %s", node, problem)
+    }
+  }
+}
+
+// NodeHealth is a copy of the last health of every node
+func (ge *GameEngine) NodeHealth() map[string]NodeHealth {
+  ge.healthlock.Lock()
+  defer ge.healthlock.Unlock()
+  health := map[string]NodeHealth{}
+  for node, nh := range ge.health {
+    health[node] = *nh
+  }
+  return health
+}
+
+// SelfTest asks one node (or all nodes without a name) to test its sensors, the results come back in a SELFTEST_RESULT
+func (ge *GameEngine) SelfTest(node string) error {
+  name := constants.NODE_SELFTEST
+  if node != "" {
+    name = strings.Join([]string{node, constants.NODE_SELFTEST}, constants.SPLIT)
+  }
+  return ge.SendEventToNodes(NewGameEvent(name, []byte(ge.conf.SelfTestWindow)))
+}
+
+// Identify flashes the sensors of a node so it can be found in the field
+func (ge *GameEngine) Identify(node string) error {
+  if node == "" {
+    return constants.ERR_NO_NODE
+  }
+  return ge.SendEventToNodes(NewGameEvent(strings.Join([]string{node, constants.NODE_IDENTIFY}, constants.SPLIT), []byte{}))
+}
+
+// UpdateSensor pushes a sensor change to a node, which restarts just that sensor and saves it (see SensorsConfig.Update)
+func (ge *GameEngine) UpdateSensor(node, update string) error {
+  if node == "" {
+    return constants.ERR_NO_NODE
+  }
+
+  if _, _, ok := strings.Cut(update, constants.SPLIT); !ok {
+    return constants.ERR_INVALID_SENSOR_UPDATE
+  }
+  return ge.SendEventToNodes(NewGameEvent(strings.Join([]string{node, constants.SENSOR_CONFIG}, constants.SPLIT), []byte(update)))
+}
+
+func (ge *GameEngine) AddSelfTest(result *SelfTestResult) {
+  ge.healthlock.Lock()
+  defer ge.healthlock.Unlock()
+  ge.selftests[result.Node] = result
+}
+
+// SelfTests is a copy of the last self test of every node that ran one
+func (ge *GameEngine) SelfTests() map[string]SelfTestResult {
+  ge.healthlock.Lock()
+  defer ge.healthlock.Unlock()
+  selftests := map[string]SelfTestResult{}
+  for node, result := range ge.selftests {
+    selftests[node] = *result
+  }
+  return selftests
+}
@@ -0,0 +1,47 @@
+package game
+
+import (
+  "log"
+  "time"
+  "context"
+
+  "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// GameKingOfTheHill lights a single "hill" node at a time and only counts hits
+// landed on it, moving the hill to another node every interval
+type GameKingOfTheHill struct {
+  GameBase
+  interval      time.Duration
+}
+
+func NewGameKingOfTheHill(id, mode string, cfg *config.Config, gamechan *GameChannel, logger *log.Logger) *GameKingOfTheHill {
+  logger = log.New(logger.Writer(), "[KINGOFTHEHILL]: ", logger.Flags())
+
+  interval, err := time.ParseDuration(cfg.HillInterval)
+  if err != nil || interval <= 0 {
+    logger.Printf("invalid hill interval '%s', using %s", cfg.HillInterval, constants.DEFAULT_HILL_INTERVAL)
+    interval = constants.DEFAULT_HILL_INTERVAL
+  }
+
+  return &GameKingOfTheHill{
+    GameBase:       NewGameBase(id, mode, cfg, gamechan, logger),
+    interval:       interval,
+  }
+}
+
+func (g *GameKingOfTheHill) Start(ctx context.Context) error {
+  g.Printf("starting game %s (hill moves every %s)", g, g.interval)
+  g.Begin("starting king of the hill!")
+  g.Request(constants.RANDOM_HILL, "choosing the first hill")
+
+  timer := g.NewTimer(g.interval, true, func() {
+    g.Request(constants.RANDOM_HILL, "moving the hill")
+  })
+  return g.Run(ctx, timer, nil)
+}
+
+func (g *GameKingOfTheHill) String() string {
+  return constants.GAME_MODE_KING_OF_THE_HILL
+}
@@ -0,0 +1,103 @@
+package game
+
+import (
+  "cmp"
+  "fmt"
+  "time"
+  "slices"
+  "encoding/json"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// HitRecord is a single hit recorded by a node, with when it landed by each of the node's clocks
+type HitRecord struct {
+  Id            string          `yaml:"id" json:"id"`
+  Node          string          `yaml:"node" json:"node"`
+  GameId        string          `yaml:"game_id" json:"game_id"`
+  Run           string          `yaml:"run" json:"run"` // the node process that recorded the hit, sequence numbers are only unique within a run
+  Seq           uint64          `yaml:"seq" json:"seq"` // numbered in the order the node recorded the game's hits
+  SensorId      string          `yaml:"sensor_id" json:"sensor_id"`
+  Color         string          `yaml:"color" json:"color"` // the sensor color when it was hit
+  Team          string          `yaml:"team" json:"team"` // empty when nobody was credited (i.e. a capture)
+  Hits          int             `yaml:"hits" json:"hits"`
+  Points        int             `yaml:"points" json:"points"`
+  Mono          time.Duration   `yaml:"mono" json:"mono"` // since the node started, unaffected by changes to the wall clock
+  At            time.Time       `yaml:"at" json:"at"`
+  LTime         uint64          `yaml:"ltime" json:"ltime"` // serf lamport time of the node's events, orders hits against game events
+}
+
+// HitRecordPage is a page of a node's hit records, small enough to fit a serf query response
+type HitRecordPage struct {
+  Records       []HitRecord     `yaml:"records" json:"records"`
+  Total         int             `yaml:"total" json:"total"`
+}
+
+// GetHitRecords collects the hit records of a game from every node, the first page from all nodes at once
+// then the rest node by node, in lamport then wall clock order. It is partial when a game node did not answer
+// or stopped answering before all of its records were in
+func (ge *GameEngine) GetHitRecords(gameid string) ([]HitRecord, bool, error) {
+  records := []HitRecord{}
+  partial := false
+
+  resp, err := ge.SendQueryToNodes(ge.HitRecordsQuery(gameid, 0, []string{}))
+  if err != nil {
+    return records, true, err
+  }
+
+  for _, node := range ge.conf.Nodes {
+    if _, ok := resp[node]; !ok {
+      ge.Printf("%s did not answer with its hit records", node)
+      partial = true
+    }
+  }
+
+  for node, data := range resp {
+    offset := 0
+    for {
+      var page HitRecordPage
+      if err := json.Unmarshal(data, &page); err != nil {
+        ge.Printf("cannot parse hit records from %s: %s", node, err)
+        partial = true
+        break
+      }
+
+      records = append(records, page.Records...)
+      offset += len(page.Records)
+      if offset >= page.Total {
+        break
+      }
+
+      if len(page.Records) == 0 {
+        ge.Printf("%s sent no hit records after %d of %d", node, offset, page.Total)
+        partial = true
+        break
+      }
+
+      next, err := ge.SendQueryToNodes(ge.HitRecordsQuery(gameid, offset, []string{node}))
+      if err != nil {
+        return records, true, err
+      }
+
+      var ok bool
+      if data, ok = next[node]; !ok {
+        ge.Printf("%s stopped answering after %d of %d hit records", node, offset, page.Total)
+        partial = true
+        break
+      }
+    }
+  }
+
+  slices.SortStableFunc(records, func(a, b HitRecord) int {
+    if c := cmp.Compare(a.LTime, b.LTime); c != 0 {
+      return c
+    }
+    return a.At.Compare(b.At)
+  })
+  return records, partial, nil
+}
+
+func (ge *GameEngine) HitRecordsQuery(gameid string, offset int, nodes []string) GameQuery {
+  q := NewGameQuery(constants.NODE_HITS, []byte(fmt.Sprintf("%s%s%d", gameid, constants.SPLIT, offset)), ge.NodeTags())
+  q.Nodes = nodes
+  return q
+}
@@ -0,0 +1,127 @@
+package game
+
+import (
+  "fmt"
+  "sync"
+  "time"
+  "slices"
+  "github.com/google/uuid"
+  "github.com/taemon1337/arena-nerf/pkg/common"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// MatchRound is the result of a single game played as part of a match
+type MatchRound struct {
+  Round             int             `yaml:"round" json:"round"`
+  GameId            string          `yaml:"game_id" json:"game_id"`
+  Log               string          `yaml:"log" json:"log"` // the round's game log (see GameEngine.Logfile)
+  Status            string          `yaml:"status" json:"status"`
+  Scoreboard        map[string]int  `yaml:"scoreboard" json:"scoreboard"`
+  Winners           []string        `yaml:"winners" json:"winners"`
+}
+
+// Match is a best-of-N series of games of the same mode
+type Match struct {
+  Id                string          `yaml:"id" json:"id"`
+  Mode              string          `yaml:"mode" json:"mode"`
+  Rounds            int             `yaml:"rounds" json:"rounds"`
+  RoundBreak        time.Duration   `yaml:"round_break" json:"round_break"`
+  Status            string          `yaml:"status" json:"status"`
+  Results           []MatchRound    `yaml:"results" json:"results"`
+  RoundWins         map[string]int  `yaml:"round_wins" json:"round_wins"`
+  Scoreboard        map[string]int  `yaml:"scoreboard" json:"scoreboard"` // points across every round
+  Winner            string          `yaml:"winner" json:"winner"`
+  Winners           []string        `yaml:"winners" json:"winners"`
+  StartedAt         time.Time       `yaml:"started_at" json:"started_at"`
+  EndedAt           time.Time       `yaml:"ended_at" json:"ended_at"`
+  clock             common.Clock    `yaml:"-" json:"-"`
+  matchlock         *sync.Mutex     `yaml:"-" json:"-"`
+}
+
+func NewMatch(mode string, rounds int, roundbreak time.Duration, clock common.Clock) *Match {
+  return &Match{
+    Id:             uuid.New().String(),
+    Mode:           mode,
+    Rounds:         rounds,
+    RoundBreak:     roundbreak,
+    Status:         constants.GAME_STATUS_RUNNING,
+    Results:        []MatchRound{},
+    RoundWins:      map[string]int{},
+    Scoreboard:     map[string]int{},
+    Winner:         "",
+    Winners:        []string{},
+    StartedAt:      clock.Now(),
+    EndedAt:        time.Time{},
+    clock:          clock,
+    matchlock:      &sync.Mutex{},
+  }
+}
+
+// WinsNeeded is the number of rounds a team must win to clinch the match
+func (m *Match) WinsNeeded() int {
+  return m.Rounds / 2 + 1
+}
+
+// AddRound records a finished game, only counting a round win when it was not a draw
+func (m *Match) AddRound(round int, gameid, logname string, gs *GameState) {
+  m.matchlock.Lock()
+  defer m.matchlock.Unlock()
+
+  scoreboard := map[string]int{}
+  for team, points := range gs.Scoreboard {
+    scoreboard[team] = points
+    m.Scoreboard[team] += points
+  }
+
+  if len(gs.Winners) == 1 && gs.Status == constants.GAME_STATUS_ENDED {
+    m.RoundWins[gs.Winners[0]] += 1
+  }
+
+  m.Results = append(m.Results, MatchRound{
+    Round:        round,
+    GameId:       gameid,
+    Log:          logname,
+    Status:       gs.Status,
+    Scoreboard:   scoreboard,
+    Winners:      gs.Winners,
+  })
+}
+
+// Clinched returns the team that has won the majority of rounds, if any
+func (m *Match) Clinched() string {
+  for team, wins := range m.RoundWins {
+    if wins >= m.WinsNeeded() {
+      return team
+    }
+  }
+  return ""
+}
+
+// EndMatch declares the team(s) with the most round wins the winner
+func (m *Match) EndMatch() {
+  m.matchlock.Lock()
+  defer m.matchlock.Unlock()
+  m.Status = constants.GAME_STATUS_ENDED
+  m.EndedAt = m.clock.Now()
+
+  winners := []string{}
+  mostwins := 0
+  for team, wins := range m.RoundWins {
+    if wins > mostwins {
+      winners = []string{team}
+      mostwins = wins
+    } else if wins == mostwins {
+      winners = append(winners, team)
+    }
+  }
+
+  slices.Sort(winners)
+  m.Winners = winners
+  if len(winners) == 1 {
+    m.Winner = winners[0]
+  }
+}
+
+func (m *Match) String() string {
+  return fmt.Sprintf("%s best of %d (round wins: %v)", m.Mode, m.Rounds, m.RoundWins)
+}
@@ -0,0 +1,172 @@
+package game
+
+import (
+  "fmt"
+  "sync"
+  "time"
+  "slices"
+  "context"
+  "github.com/google/uuid"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// QueuedGame is a game waiting its turn, it starts at a set time or a number of seconds after the previous game
+type QueuedGame struct {
+  Id                string          `yaml:"id" json:"id"`
+  Mode              string          `yaml:"mode" json:"mode"`
+  GameOverrides                     `yaml:",inline"`
+  StartAt           time.Time       `yaml:"start_at" json:"start_at"`
+  AfterSeconds      int             `yaml:"after_seconds" json:"after_seconds"` // only used without a start time
+  QueuedAt          time.Time       `yaml:"queued_at" json:"queued_at"`
+}
+
+// GameQueue is the list of games to run back-to-back, in order
+type GameQueue struct {
+  Games             []*QueuedGame   `yaml:"games" json:"games"`
+  queuelock         *sync.Mutex     `yaml:"-" json:"-"`
+}
+
+func NewGameQueue() *GameQueue {
+  return &GameQueue{
+    Games:          []*QueuedGame{},
+    queuelock:      &sync.Mutex{},
+  }
+}
+
+// Due is true once the queued game may start, given when the previous game ended
+func (qg *QueuedGame) Due(now, lastended time.Time) bool {
+  if !qg.StartAt.IsZero() {
+    return !now.Before(qg.StartAt)
+  }
+  return !now.Before(lastended.Add(time.Duration(qg.AfterSeconds) * time.Second))
+}
+
+func (qg *QueuedGame) String() string {
+  if !qg.StartAt.IsZero() {
+    return fmt.Sprintf("%s %s at %s", qg.Mode, qg.Id, qg.StartAt.Format(time.RFC3339))
+  }
+  return fmt.Sprintf("%s %s %ds after the previous game", qg.Mode, qg.Id, qg.AfterSeconds)
+}
+
+func (q *GameQueue) Add(qg *QueuedGame) {
+  q.queuelock.Lock()
+  defer q.queuelock.Unlock()
+  qg.Id = uuid.New().String()
+  q.Games = append(q.Games, qg)
+}
+
+// List is a copy of the queue, safe to hand out
+func (q *GameQueue) List() []*QueuedGame {
+  q.queuelock.Lock()
+  defer q.queuelock.Unlock()
+  return slices.Clone(q.Games)
+}
+
+// Next is the game at the head of the queue, if it is due
+func (q *GameQueue) Next(now, lastended time.Time) *QueuedGame {
+  q.queuelock.Lock()
+  defer q.queuelock.Unlock()
+  if len(q.Games) == 0 || !q.Games[0].Due(now, lastended) {
+    return nil
+  }
+  return q.Games[0]
+}
+
+// Move puts a queued game at a new position (0 is next up)
+func (q *GameQueue) Move(id string, position int) error {
+  q.queuelock.Lock()
+  defer q.queuelock.Unlock()
+
+  i := q.index(id)
+  if i < 0 {
+    return constants.ERR_QUEUED_GAME_NOT_FOUND
+  }
+  if position < 0 || position >= len(q.Games) {
+    return constants.ERR_INVALID_QUEUE_POSITION
+  }
+
+  qg := q.Games[i]
+  q.Games = slices.Insert(slices.Delete(q.Games, i, i+1), position, qg)
+  return nil
+}
+
+// Cancel takes a game off the queue
+func (q *GameQueue) Cancel(id string) error {
+  q.queuelock.Lock()
+  defer q.queuelock.Unlock()
+
+  i := q.index(id)
+  if i < 0 {
+    return constants.ERR_QUEUED_GAME_NOT_FOUND
+  }
+  q.Games = slices.Delete(q.Games, i, i+1)
+  return nil
+}
+
+func (q *GameQueue) index(id string) int {
+  return slices.IndexFunc(q.Games, func(qg *QueuedGame) bool { return qg.Id == id })
+}
+
+// QueueGame checks a game can be played before adding it to the end of the queue
+func (ge *GameEngine) QueueGame(qg *QueuedGame) error {
+  if err := qg.GameOverrides.Validate(); err != nil {
+    return err
+  }
+
+  if NewGame(qg.Mode, ge.conf, ge.gamechan, ge.Logger) == nil {
+    return constants.ERR_UNSUPPORTED_GAME_MODE
+  }
+
+  qg.QueuedAt = ge.clock.Now()
+  ge.Queue.Add(qg)
+  ge.Printf("queued game %s", qg)
+  return nil
+}
+
+// RunQueue starts the next queued game whenever the engine is free and the game is due
+func (ge *GameEngine) RunQueue(ctx context.Context) error {
+  ticker := time.NewTicker(1 * time.Second)
+  defer ticker.Stop()
+
+  for {
+    select {
+      case <-ticker.C:
+      case <-ctx.Done():
+        return ctx.Err()
+    }
+
+    if ge.Busy() {
+      continue
+    }
+
+    qg := ge.Queue.Next(ge.clock.Now(), ge.lastended)
+    if qg == nil {
+      continue
+    }
+
+    if err := ge.Queue.Cancel(qg.Id); err != nil {
+      continue // taken off the queue in the meantime
+    }
+
+    if err := ge.StartQueuedGame(ctx, qg); err != nil {
+      ge.Printf("cannot play queued game %s: %s", qg, err)
+    }
+  }
+}
+
+// StartQueuedGame mounts and plays a queued game, waiting for the nodes like any other game
+func (ge *GameEngine) StartQueuedGame(ctx context.Context, qg *QueuedGame) error {
+  ge.Printf("starting queued game %s", qg)
+  if err := ge.NewGame(qg.Mode, &qg.GameOverrides); err != nil {
+    return err
+  }
+
+  if err := ge.StartGame(ctx); err != nil {
+    if ge.CurrentGameState.Status == constants.GAME_STATUS_INIT {
+      ge.CloseGameOver()
+      ge.CurrentGame = nil // never got going, free the engine for the next one
+    }
+    return err
+  }
+  return nil
+}
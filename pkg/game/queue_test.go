@@ -0,0 +1,114 @@
+package game
+
+import (
+  "time"
+  "slices"
+  "testing"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// newTestQueue queues one game per mode, in order, returning the queue and the game ids
+func newTestQueue(modes ...string) (*GameQueue, []string) {
+  q := NewGameQueue()
+  ids := []string{}
+  for _, mode := range modes {
+    qg := &QueuedGame{Mode: mode}
+    q.Add(qg)
+    ids = append(ids, qg.Id)
+  }
+  return q, ids
+}
+
+func queuedModes(q *GameQueue) []string {
+  modes := []string{}
+  for _, qg := range q.List() {
+    modes = append(modes, qg.Mode)
+  }
+  return modes
+}
+
+func TestQueueMove(t *testing.T) {
+  tests := []struct {
+    name      string
+    game      int     // index of the game to move, -1 for one not on the queue
+    position  int
+    want      []string
+    err       error
+  }{
+    {"to the front", 2, 0, []string{"c", "a", "b", "d"}, nil},
+    {"to the back", 0, 3, []string{"b", "c", "d", "a"}, nil},
+    {"down one", 1, 2, []string{"a", "c", "b", "d"}, nil},
+    {"up one", 2, 1, []string{"a", "c", "b", "d"}, nil},
+    {"in place", 1, 1, []string{"a", "b", "c", "d"}, nil},
+    {"past the back", 0, 4, []string{"a", "b", "c", "d"}, constants.ERR_INVALID_QUEUE_POSITION},
+    {"before the front", 0, -1, []string{"a", "b", "c", "d"}, constants.ERR_INVALID_QUEUE_POSITION},
+    {"not queued", -1, 0, []string{"a", "b", "c", "d"}, constants.ERR_QUEUED_GAME_NOT_FOUND},
+  }
+
+  for _, tt := range tests {
+    q, ids := newTestQueue("a", "b", "c", "d")
+    id := "missing"
+    if tt.game >= 0 {
+      id = ids[tt.game]
+    }
+
+    if err := q.Move(id, tt.position); err != tt.err {
+      t.Errorf("%s: got error %v, want %v", tt.name, err, tt.err)
+    }
+    if got := queuedModes(q); !slices.Equal(got, tt.want) {
+      t.Errorf("%s: queue is %v, want %v", tt.name, got, tt.want)
+    }
+  }
+}
+
+func TestQueueCancel(t *testing.T) {
+  q, ids := newTestQueue("a", "b", "c")
+
+  if err := q.Cancel(ids[1]); err != nil {
+    t.Fatalf("cannot cancel queued game: %s", err)
+  }
+  if got := queuedModes(q); !slices.Equal(got, []string{"a", "c"}) {
+    t.Fatalf("queue is %v after cancelling b", got)
+  }
+
+  if err := q.Cancel(ids[1]); err != constants.ERR_QUEUED_GAME_NOT_FOUND {
+    t.Fatalf("got %v cancelling b again, want %s", err, constants.ERR_QUEUED_GAME_NOT_FOUND)
+  }
+
+  // the list handed out is a copy, cancelling does not change it
+  list := q.List()
+  if err := q.Cancel(ids[0]); err != nil {
+    t.Fatalf("cannot cancel queued game: %s", err)
+  }
+  if len(list) != 2 || list[0].Id != ids[0] {
+    t.Fatalf("listed queue changed by a cancel")
+  }
+  if got := queuedModes(q); !slices.Equal(got, []string{"c"}) {
+    t.Fatalf("queue is %v after cancelling a", got)
+  }
+}
+
+func TestQueueNext(t *testing.T) {
+  ended := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+  q := NewGameQueue()
+  if q.Next(ended, ended) != nil {
+    t.Fatalf("empty queue has a next game")
+  }
+
+  q.Add(&QueuedGame{Mode: "a", AfterSeconds: 30})
+  if q.Next(ended.Add(29 * time.Second), ended) != nil {
+    t.Fatalf("game due 29s after the last one ended")
+  }
+  if qg := q.Next(ended.Add(30 * time.Second), ended); qg == nil || qg.Mode != "a" {
+    t.Fatalf("game not due 30s after the last one ended")
+  }
+
+  // a set start time wins over the wait after the last game
+  qg := &QueuedGame{Mode: "b", StartAt: ended.Add(time.Hour), AfterSeconds: 0}
+  if qg.Due(ended.Add(time.Minute), ended) {
+    t.Fatalf("game due before its start time")
+  }
+  if !qg.Due(ended.Add(time.Hour), ended) {
+    t.Fatalf("game not due at its start time")
+  }
+}
@@ -0,0 +1,224 @@
+package game
+
+import (
+  "os"
+  "fmt"
+  "errors"
+  "sync"
+  "slices"
+  "strings"
+  "path/filepath"
+  "gopkg.in/yaml.v2"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+var (
+  gamerules = map[string]*GameRules{}
+  ruleslock = &sync.Mutex{}
+)
+
+// GameRules declares a game mode in yaml so new formats can be played without a rebuild,
+// anything left out keeps the default game config (and the flags)
+type GameRules struct {
+  Name              string          `yaml:"name" json:"name"`
+  Description       string          `yaml:"description" json:"description"`
+  GameLength        string          `yaml:"game_length" json:"game_length"`
+  WinningScore      *int            `yaml:"winning_score" json:"winning_score"`
+  EndConditions     []string        `yaml:"end_conditions" json:"end_conditions"`
+  MinTeamCount      int             `yaml:"min_team_count" json:"min_team_count"`
+  MaxTeamCount      int             `yaml:"max_team_count" json:"max_team_count"`
+  PointsPerHit      *int            `yaml:"points_per_hit" json:"points_per_hit"`
+  SensorPoints      map[string]int  `yaml:"sensor_points" json:"sensor_points"` // keyed by <node> or <node>:<sensor-id>
+  Lighting          string          `yaml:"lighting" json:"lighting"`
+  LightInterval     string          `yaml:"light_interval" json:"light_interval"`
+}
+
+// NodeRules are the parts of the game rules a node needs to score and accept hits
+type NodeRules struct {
+  Lighting          string          `yaml:"lighting" json:"lighting"`
+  PointsPerHit      int             `yaml:"points_per_hit" json:"points_per_hit"`
+  SensorPoints      map[string]int  `yaml:"sensor_points" json:"sensor_points"` // keyed by sensor id
+}
+
+func NewGameRules() *GameRules {
+  return &GameRules{
+    Name:           "",
+    Description:    "",
+    GameLength:     "",
+    WinningScore:   nil,
+    EndConditions:  nil,
+    MinTeamCount:   0,
+    MaxTeamCount:   0,
+    PointsPerHit:   nil,
+    SensorPoints:   map[string]int{},
+    Lighting:       constants.LIGHTING_RANDOM,
+    LightInterval:  "3s",
+  }
+}
+
+func NewNodeRules() *NodeRules {
+  return &NodeRules{
+    Lighting:       "",
+    PointsPerHit:   1,
+    SensorPoints:   map[string]int{},
+  }
+}
+
+// NewNodeRulesFor picks out the rules for a single node from the game config
+func NewNodeRulesFor(node string, gc *GameConfig) *NodeRules {
+  nr := NewNodeRules()
+  nr.Lighting = gc.Lighting
+  nr.PointsPerHit = gc.PointsPerHit
+
+  for key, points := range gc.SensorPoints {
+    if key == node {
+      nr.PointsPerHit = points
+    } else if sensorid, ok := strings.CutPrefix(key, node + constants.SPLIT); ok {
+      nr.SensorPoints[sensorid] = points
+    }
+  }
+
+  // civilians cost points whatever else they would have been worth
+  for _, key := range gc.Civilians {
+    if key == node {
+      nr.PointsPerHit = gc.CivilianPoints
+      for sensorid, _ := range nr.SensorPoints {
+        nr.SensorPoints[sensorid] = gc.CivilianPoints
+      }
+    } else if sensorid, ok := strings.CutPrefix(key, node + constants.SPLIT); ok {
+      nr.SensorPoints[sensorid] = gc.CivilianPoints
+    }
+  }
+
+  return nr
+}
+
+// Points returns what a single hit on the sensor is worth
+func (nr *NodeRules) Points(sensorid string) int {
+  if points, ok := nr.SensorPoints[sensorid]; ok {
+    return points
+  }
+  return nr.PointsPerHit
+}
+
+func (r *GameRules) Validate() error {
+  if r.Name == "" {
+    return constants.ERR_NO_GAME_RULES_NAME
+  }
+
+  if IsBuiltInMode(r.Name) {
+    return constants.ERR_BUILT_IN_GAME_MODE
+  }
+
+  switch r.Lighting {
+    case constants.LIGHTING_NONE, constants.LIGHTING_RANDOM, constants.LIGHTING_ALL, constants.LIGHTING_TARGET, constants.LIGHTING_HILL:
+    default:
+      return constants.ERR_INVALID_LIGHTING
+  }
+
+  for _, cond := range r.EndConditions {
+    if !slices.Contains([]string{constants.END_ON_TIME, constants.END_ON_SCORE}, cond) {
+      return constants.ERR_INVALID_END_CONDITION
+    }
+  }
+
+  return nil
+}
+
+// Configure applies the rules on top of the default game config
+func (r *GameRules) Configure(gc *GameConfig) {
+  if r.GameLength != "" {
+    gc.GameLength = r.GameLength
+  }
+  if r.MinTeamCount > 0 {
+    gc.MinTeamCount = r.MinTeamCount
+  }
+  if r.MaxTeamCount > 0 {
+    gc.MaxTeamCount = r.MaxTeamCount
+  }
+  if r.WinningScore != nil {
+    gc.WinningScore = *r.WinningScore
+  }
+  if r.EndConditions != nil {
+    gc.EndConditions = r.EndConditions
+  }
+  if r.PointsPerHit != nil {
+    gc.PointsPerHit = *r.PointsPerHit
+  }
+  if gc.SensorPoints == nil {
+    gc.SensorPoints = map[string]int{}
+  }
+  for key, points := range r.SensorPoints {
+    gc.SensorPoints[key] = points // the mode's points win over the -points flags
+  }
+  gc.Lighting = r.Lighting
+}
+
+func LoadGameRulesFile(path string) (*GameRules, error) {
+  rules := NewGameRules()
+
+  data, err := os.ReadFile(path)
+  if err != nil {
+    return nil, err
+  }
+
+  if err := yaml.Unmarshal(data, rules); err != nil {
+    return nil, fmt.Errorf("%s: %s", path, err)
+  }
+
+  if err := rules.Validate(); err != nil {
+    return nil, fmt.Errorf("%s: %w", path, err)
+  }
+
+  return rules, nil
+}
+
+// LoadGameRules reads every yaml game mode in the directory, a bad file (or a second mode with the same name)
+// is skipped and its error returned so it cannot keep the good ones from loading
+func LoadGameRules(dir string) ([]*GameRules, error) {
+  loaded := []*GameRules{}
+  errs := []error{}
+
+  entries, err := os.ReadDir(dir)
+  if err != nil {
+    return loaded, err
+  }
+
+  for _, e := range entries {
+    if e.IsDir() || !(strings.HasSuffix(e.Name(), ".yaml") || strings.HasSuffix(e.Name(), ".yml")) {
+      continue
+    }
+
+    path := filepath.Join(dir, e.Name())
+    rules, err := LoadGameRulesFile(path)
+    if err != nil {
+      errs = append(errs, err)
+      continue
+    }
+
+    if slices.ContainsFunc(loaded, func(r *GameRules) bool { return r.Name == rules.Name }) {
+      errs = append(errs, fmt.Errorf("%s: %w %s", path, constants.ERR_DUPLICATE_GAME_MODE, rules.Name))
+      continue
+    }
+
+    loaded = append(loaded, rules)
+  }
+
+  return loaded, errors.Join(errs...)
+}
+
+// RegisterGameRules makes a yaml game mode available to NewGame by its name
+func RegisterGameRules(rules *GameRules) {
+  ruleslock.Lock()
+  defer ruleslock.Unlock()
+  gamerules[rules.Name] = rules
+}
+
+func GetGameRules(name string) *GameRules {
+  ruleslock.Lock()
+  defer ruleslock.Unlock()
+  if rules, ok := gamerules[name]; ok {
+    return rules
+  }
+  return nil
+}
@@ -0,0 +1,113 @@
+package game
+
+import (
+  "os"
+  "log"
+  "errors"
+  "slices"
+  "testing"
+  "path/filepath"
+  "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+func TestGameRulesConfigure(t *testing.T) {
+  cfg := config.NewConfig(log.Default())
+  cfg.WinningScore = 25
+
+  // left out of the yaml, so the flags and defaults stand
+  gc := NewGameConfig(cfg)
+  NewGameRules().Configure(gc)
+  if gc.WinningScore != 25 {
+    t.Errorf("winning score %d, want the flag's 25", gc.WinningScore)
+  }
+  if !slices.Equal(gc.EndConditions, []string{constants.END_ON_TIME, constants.END_ON_SCORE}) {
+    t.Errorf("end conditions %v, want the defaults", gc.EndConditions)
+  }
+  if gc.PointsPerHit != 1 {
+    t.Errorf("points per hit %d, want the default 1", gc.PointsPerHit)
+  }
+
+  // set in the yaml, even to zero
+  zero, three := 0, 3
+  rules := NewGameRules()
+  rules.WinningScore = &zero
+  rules.PointsPerHit = &three
+  rules.EndConditions = []string{constants.END_ON_TIME}
+
+  gc = NewGameConfig(cfg)
+  rules.Configure(gc)
+  if gc.WinningScore != 0 {
+    t.Errorf("winning score %d, want the mode's 0", gc.WinningScore)
+  }
+  if !slices.Equal(gc.EndConditions, []string{constants.END_ON_TIME}) {
+    t.Errorf("end conditions %v, want the mode's [time]", gc.EndConditions)
+  }
+  if gc.PointsPerHit != 3 {
+    t.Errorf("points per hit %d, want the mode's 3", gc.PointsPerHit)
+  }
+}
+
+func TestLoadGameRules(t *testing.T) {
+  dir := t.TempDir()
+  files := map[string]string{
+    "a-blitz.yaml":     "name: blitz\nwinning_score: 30\n",
+    "b-blitz.yaml":     "name: blitz\nwinning_score: 50\n",   // same name, skipped
+    "c-hill.yaml":      "name: " + constants.GAME_MODE_KING_OF_THE_HILL + "\n",   // built in, skipped
+    "d-bad.yaml":       "name: [\n",    // does not parse, skipped
+    "e-sprint.yml":     "name: sprint\nlighting: target\n",
+    "notes.txt":        "not a game mode",
+  }
+  for name, data := range files {
+    if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+      t.Fatalf("cannot write %s: %s", name, err)
+    }
+  }
+
+  loaded, err := LoadGameRules(dir)
+  if err == nil {
+    t.Fatalf("no error for the skipped files")
+  }
+  if !errors.Is(err, constants.ERR_BUILT_IN_GAME_MODE) {
+    t.Errorf("no built in mode error in: %s", err)
+  }
+
+  names := []string{}
+  for _, rules := range loaded {
+    names = append(names, rules.Name)
+  }
+  if !slices.Equal(names, []string{"blitz", "sprint"}) {
+    t.Fatalf("loaded %v, want [blitz sprint]", names)
+  }
+  if *loaded[0].WinningScore != 30 {
+    t.Errorf("blitz winning score %d, want the first file's 30", *loaded[0].WinningScore)
+  }
+}
+
+func TestNewNodeRulesFor(t *testing.T) {
+  gc := NewGameConfig(config.NewConfig(log.Default()))
+  gc.PointsPerHit = 1
+  gc.SensorPoints = map[string]int{"node1": 2, "node1:one": 5, "node2:two": 3, "node10:one": 9}
+  gc.Civilians = []string{"node1:four", "node3"}
+  gc.CivilianPoints = -5
+
+  tests := []struct {
+    node    string
+    sensor  string
+    want    int
+  }{
+    {"node1", "one", 5},      // the sensor wins over the node
+    {"node1", "two", 2},      // the node's points
+    {"node1", "four", -5},    // a civilian
+    {"node2", "two", 3},
+    {"node2", "one", 1},      // the game's points per hit
+    {"node3", "one", -5},     // every sensor of a civilian node
+    {"node10", "two", 1},     // node1 points are not node10's
+  }
+
+  for _, tt := range tests {
+    if got := NewNodeRulesFor(tt.node, gc).Points(tt.sensor); got != tt.want {
+      t.Errorf("hit on %s:%s worth %d, want %d", tt.node, tt.sensor, got, tt.want)
+    }
+  }
+}
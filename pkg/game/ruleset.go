@@ -0,0 +1,74 @@
+package game
+
+import (
+  "log"
+  "time"
+  "context"
+
+  "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// GameRuleset runs any game mode declared in yaml (see GameRules)
+type GameRuleset struct {
+  GameBase
+  rules         *GameRules
+  interval      time.Duration
+}
+
+func NewGameRuleset(id, mode string, rules *GameRules, cfg *config.Config, gamechan *GameChannel, logger *log.Logger) *GameRuleset {
+  logger = log.New(logger.Writer(), "[RULESET:" + rules.Name + "]: ", logger.Flags())
+
+  interval, err := time.ParseDuration(rules.LightInterval)
+  if err != nil || interval <= 0 {
+    logger.Printf("invalid light interval '%s', using %s", rules.LightInterval, constants.DEFAULT_LIGHT_INTERVAL)
+    interval = constants.DEFAULT_LIGHT_INTERVAL
+  }
+
+  return &GameRuleset{
+    GameBase:       NewGameBase(id, mode, cfg, gamechan, logger),
+    rules:          rules,
+    interval:       interval,
+  }
+}
+
+func (g *GameRuleset) Configure(gc *GameConfig) {
+  g.rules.Configure(gc)
+}
+
+func (g *GameRuleset) Start(ctx context.Context) error {
+  g.Printf("starting game %s - %s", g, g.rules.Description)
+  g.Begin("starting " + g.rules.Name + "!")
+  g.Light()
+
+  deadline := g.NewTimer(g.interval, true, g.Light)
+
+  return g.Run(ctx, deadline, func(evt GameEvent) {
+    switch evt.Event {
+      case constants.TARGET_HIT:
+        g.Printf("target hit: %s", string(evt.Payload))
+        g.Light()
+        deadline.Reset()
+      default:
+        g.Printf("unrecognized %s event: %s", g.rules.Name, evt.Event)
+    }
+  })
+}
+
+// Light asks the engine to change which sensors are lit based on the lighting rule
+func (g *GameRuleset) Light() {
+  switch g.rules.Lighting {
+    case constants.LIGHTING_RANDOM:
+      g.Request(constants.RANDOM_SENSOR_COLOR, "lighting a random sensor")
+    case constants.LIGHTING_ALL:
+      g.Request(constants.RANDOM_SENSOR_COLORS, "lighting all sensors")
+    case constants.LIGHTING_TARGET:
+      g.Request(constants.RANDOM_TARGET, "lighting a new target")
+    case constants.LIGHTING_HILL:
+      g.Request(constants.RANDOM_HILL, "moving the hill")
+  }
+}
+
+func (g *GameRuleset) String() string {
+  return g.rules.Name
+}
@@ -20,3 +20,26 @@ func (ge *GameEngine) Logstats() error {
   return os.WriteFile(ge.Logfile(), data, os.ModePerm)
 }
 
+func (ge *GameEngine) Matchfile() string {
+  return filepath.Join(ge.conf.Logdir, fmt.Sprintf("match-%s.json", ge.CurrentMatch.Id))
+}
+
+func (ge *GameEngine) LogMatch() error {
+  if ge.conf.Logdir == "" {
+    return nil
+  }
+
+  data, err := json.Marshal(ge.CurrentMatch)
+  if err != nil {
+    return err
+  }
+
+  if err := os.WriteFile(ge.Matchfile(), data, os.ModePerm); err != nil {
+    ge.Printf("could not write match log: %s", err)
+    return err
+  }
+
+  ge.Printf("saved match log to %s", ge.Matchfile())
+  return nil
+}
+
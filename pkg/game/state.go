@@ -8,43 +8,194 @@ import (
   "strings"
   "math/rand"
   "gopkg.in/yaml.v2"
+  "github.com/taemon1337/arena-nerf/pkg/common"
   "github.com/taemon1337/arena-nerf/pkg/constants"
 )
 
+// TargetReaction records how long it took to hit a lit target
+type TargetReaction struct {
+  Node              string          `yaml:"node" json:"node"`
+  Sensor            string          `yaml:"sensor" json:"sensor"`
+  Team              string          `yaml:"team" json:"team"`
+  LitAt             time.Time       `yaml:"lit_at" json:"lit_at"`
+  ReactionMs        int64           `yaml:"reaction_ms" json:"reaction_ms"`
+  Missed            bool            `yaml:"missed" json:"missed"`
+}
+
+// ReportedHits are the journal sequence numbers (from 1) of a node run that were counted, every one
+// up to Upto and the few above it that came in out of order, so it stays small however long the game
+type ReportedHits struct {
+  Upto              uint64          `yaml:"upto" json:"upto"`
+  Above             []uint64        `yaml:"above,omitempty" json:"above,omitempty"`
+}
+
+// Add counts a sequence number, returning false if it already was
+func (r *ReportedHits) Add(seq uint64) bool {
+  if seq <= r.Upto || slices.Contains(r.Above, seq) {
+    return false
+  }
+  r.Above = append(r.Above, seq)
+
+  // move the mark up past every number counted without a gap
+  for {
+    i := slices.Index(r.Above, r.Upto + 1)
+    if i < 0 {
+      break
+    }
+    r.Above = slices.Delete(r.Above, i, i + 1)
+    r.Upto += 1
+  }
+  return true
+}
+
+// TargetState tracks the health of a single sensor in games where targets can be eliminated
+type TargetState struct {
+  Node              string          `yaml:"node" json:"node"`
+  Sensor            string          `yaml:"sensor" json:"sensor"`
+  Health            int             `yaml:"health" json:"health"`
+  Hits              int             `yaml:"hits" json:"hits"`
+  Eliminated        bool            `yaml:"eliminated" json:"eliminated"`
+  EliminatedBy      string          `yaml:"eliminated_by" json:"eliminated_by"`
+  EliminatedAt      time.Time       `yaml:"eliminated_at" json:"eliminated_at"`
+}
+
+// OwnershipChange records when a sensor switched to another team in territory games
+type OwnershipChange struct {
+  Team              string          `yaml:"team" json:"team"`
+  At                time.Time       `yaml:"at" json:"at"`
+}
+
+// DrillStats are a solo player's personal stats for a drill
+type DrillStats struct {
+  Targets           int             `yaml:"targets" json:"targets"`
+  Hits              int             `yaml:"hits" json:"hits"`
+  Misses            int             `yaml:"misses" json:"misses"`
+  Accuracy          float64         `yaml:"accuracy" json:"accuracy"`
+  SplitsMs          []int64         `yaml:"splits_ms" json:"splits_ms"`
+  TotalMs           int64           `yaml:"total_ms" json:"total_ms"`
+}
+
+// NewDrillStats returns nil unless the game is a drill of some number of targets
+func NewDrillStats(targets int) *DrillStats {
+  if targets <= 0 {
+    return nil
+  }
+  return &DrillStats{Targets: targets, SplitsMs: []int64{}}
+}
+
+// TeamStreak is the run of hits by one team, each within the streak window of the last
+type TeamStreak struct {
+  Team              string          `yaml:"team" json:"team"`
+  Hits              int             `yaml:"hits" json:"hits"`
+  LastHitAt         time.Time       `yaml:"last_hit_at" json:"last_hit_at"`
+}
+
+// TrialRun is a single team's run through a time trial course
+type TrialRun struct {
+  Team              string          `yaml:"team" json:"team"`
+  StartedAt         time.Time       `yaml:"started_at" json:"started_at"`
+  FinishedAt        time.Time       `yaml:"finished_at" json:"finished_at"`
+  SplitsMs          []int64         `yaml:"splits_ms" json:"splits_ms"`
+  Misses            int             `yaml:"misses" json:"misses"`
+  PenaltyMs         int64           `yaml:"penalty_ms" json:"penalty_ms"`
+  TimeMs            int64           `yaml:"time_ms" json:"time_ms"` // the splits plus penalties, once finished
+  Finished          bool            `yaml:"finished" json:"finished"`
+}
+
 type GameState struct {
   config            *GameConfig     `yaml:"config" json:"config"`
   Status            string          `yaml:"status" json:"status"`
+  Seed              int64           `yaml:"seed" json:"seed"` // replays the game's randomness with -seed
+  Player            string          `yaml:"player,omitempty" json:"player,omitempty"`
   Teams             []string        `yaml:"teams" json:"teams"`
   Nodes             []string        `yaml:"nodes" json:"nodes"`
   Colors            []string        `yaml:"colors" json:"colors"`
   Scoreboard        map[string]int  `yaml:"scoreboard" json:"scoreboard"`
+  RawScoreboard     map[string]int  `yaml:"raw_scoreboard" json:"raw_scoreboard"` // team hits before handicaps and bonus points
   Nodeboard         map[string]int  `yaml:"nodeboard" json:"nodeboard"`
+  Hitboard          map[string]int  `yaml:"hitboard" json:"hitboard"` // team hits, whatever they were worth
+  Streak            TeamStreak      `yaml:"streak" json:"streak"`
+  BestStreaks       map[string]int  `yaml:"best_streaks" json:"best_streaks"`
+  Reported          map[string]*ReportedHits `yaml:"reported" json:"reported"` // the hits pushed by each <node>:<run>
   Winner            string          `yaml:"winner" json:"winner"`
+  Winners           []string        `yaml:"winners" json:"winners"`
   Highscore         int             `yaml:"highscore" json:"highscore"`
+  Phase             string          `yaml:"phase" json:"phase"`
+  TiedTeams         []string        `yaml:"tied_teams,omitempty" json:"tied_teams,omitempty"`
+  TieScore          int             `yaml:"tie_score,omitempty" json:"tie_score,omitempty"`
+  Hill              string          `yaml:"hill,omitempty" json:"hill,omitempty"`
+  Target            *TargetReaction `yaml:"target,omitempty" json:"target,omitempty"`
+  Reactions         []TargetReaction `yaml:"reactions,omitempty" json:"reactions,omitempty"`
+  Targets           map[string]*TargetState `yaml:"targets,omitempty" json:"targets,omitempty"`
+  Bonus             map[string]int  `yaml:"bonus,omitempty" json:"bonus,omitempty"`
+  Ownership         map[string][]OwnershipChange `yaml:"ownership,omitempty" json:"ownership,omitempty"`
+  Lastaccrual       time.Time       `yaml:"last_accrual" json:"last_accrual"`
+  Drill             *DrillStats     `yaml:"drill,omitempty" json:"drill,omitempty"`
+  Course            []string        `yaml:"course,omitempty" json:"course,omitempty"`
+  Trials            []*TrialRun     `yaml:"trials,omitempty" json:"trials,omitempty"`
+  BestTimeMs        int64           `yaml:"best_time_ms,omitempty" json:"best_time_ms,omitempty"`
   StartedAt         time.Time       `yaml:"StartedAt" json:"StartedAt"`
   GameDuration      time.Duration   `yaml:"GameDuration" json:"GameDuration"`
+  PausedAt          time.Time       `yaml:"paused_at" json:"paused_at"`
+  PausedDuration    time.Duration   `yaml:"paused_duration" json:"paused_duration"` // total time paused, which doesn't count against the game clock
   EndedAt           time.Time       `yaml:"EndedAt" json:"EndedAt"`
   Timeline          []GameEvent     `yaml:"timeline" json:"timeline"`
   Lastcheck         time.Time       `yaml:"last_check" json:"last_check"`
   checking          bool            `yaml:"-" json:"-"`
+  clock             common.Clock    `yaml:"-" json:"-"`
+  rand              *rand.Rand      `yaml:"-" json:"-"`
   gamelock          *sync.Mutex     `yaml:"-" json:"-"`
 }
 
 func NewGameState(cfg *GameConfig) *GameState {
+  seed := cfg.Seed
+  if seed == 0 {
+    seed = common.NewSeed()
+  }
+
+  clock := cfg.Clock
+  if clock == nil {
+    clock = common.NewRealClock()
+  }
+
   return &GameState{
     config:         cfg,
     Status:         constants.GAME_STATUS_INIT,
+    Seed:           seed,
+    Player:         cfg.Player,
     StartedAt:      time.Time{},
     EndedAt:        time.Time{},
     GameDuration:   0,
+    PausedAt:       time.Time{},
+    PausedDuration: 0,
     Teams:          cfg.Cfg.Teams,
     Nodes:          cfg.Cfg.Nodes,
     Colors:         cfg.Cfg.Colors,
     Scoreboard:     map[string]int{},
+    RawScoreboard:  map[string]int{},
     Nodeboard:      map[string]int{},
+    Hitboard:       map[string]int{},
+    Streak:         TeamStreak{},
+    BestStreaks:    map[string]int{},
+    Reported:       map[string]*ReportedHits{},
+    Winners:        []string{},
+    Phase:          constants.GAME_PHASE_REGULATION,
+    TiedTeams:      []string{},
     Timeline:       make([]GameEvent, 0),
+    Target:         nil,
+    Reactions:      []TargetReaction{},
+    Targets:        map[string]*TargetState{},
+    Bonus:          map[string]int{},
+    Ownership:      map[string][]OwnershipChange{},
+    Lastaccrual:    time.Time{},
+    Drill:          NewDrillStats(cfg.DrillTargets),
+    Course:         cfg.Course,
+    Trials:         []*TrialRun{},
+    BestTimeMs:     0,
     Lastcheck:      time.Time{},
     checking:       false,
+    clock:          clock,
+    rand:           common.NewRand(seed),
     gamelock:       &sync.Mutex{},
   }
 }
@@ -63,7 +214,7 @@ func (gs *GameState) GameSetup() error {
   gs.gamelock.Lock()
   defer gs.gamelock.Unlock()
   gs.Status = constants.GAME_STATUS_RUNNING
-  gs.StartedAt = time.Now()
+  gs.StartedAt = gs.clock.Now()
 
   dur, err := time.ParseDuration(gs.config.GameLength)
   if err != nil {
@@ -74,11 +225,82 @@ func (gs *GameState) GameSetup() error {
   return nil
 }
 
-func (gs *GameState) EndGame() {
+// StartCountdown holds the game back until the agreed start time, which is when the game clock starts
+func (gs *GameState) StartCountdown(seconds int) time.Time {
   gs.gamelock.Lock()
   defer gs.gamelock.Unlock()
+  gs.Status = constants.GAME_STATUS_STARTING
+  gs.StartedAt = gs.clock.Now().Add(time.Duration(seconds) * time.Second).Truncate(time.Millisecond)
+  return gs.StartedAt
+}
+
+// EndGame ends a running or paused game, false if it was not (i.e. already ended) so only one caller finishes it
+func (gs *GameState) EndGame() bool {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  if gs.Status != constants.GAME_STATUS_RUNNING && gs.Status != constants.GAME_STATUS_PAUSED {
+    return false
+  }
   gs.Status = constants.GAME_STATUS_ENDED
-  gs.EndedAt = time.Now()
+  gs.EndedAt = gs.clock.Now()
+  return true
+}
+
+// Restore picks a checkpointed game back up, not counting the time since it was saved against the clock
+func (gs *GameState) Restore(savedat time.Time) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  gs.rand = common.NewRand(gs.Seed)
+  gs.checking = false
+
+  if gs.Status == constants.GAME_STATUS_PAUSED {
+    savedat = gs.PausedAt // a paused game comes back running
+    gs.PausedAt = time.Time{}
+  }
+  gs.PausedDuration += gs.clock.Since(savedat)
+  gs.Status = constants.GAME_STATUS_RUNNING
+}
+
+func (gs *GameState) Abort() {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  gs.Status = constants.GAME_STATUS_ABORTED
+  gs.EndedAt = gs.clock.Now()
+}
+
+// Pause freezes the game clock
+func (gs *GameState) Pause() error {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  if gs.Status != constants.GAME_STATUS_RUNNING {
+    return constants.ERR_NO_GAME_RUNNING
+  }
+  gs.Status = constants.GAME_STATUS_PAUSED
+  gs.PausedAt = gs.clock.Now()
+  return nil
+}
+
+// Resume restarts the game clock, returning how long the game was paused
+func (gs *GameState) Resume() (time.Duration, error) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  if gs.Status != constants.GAME_STATUS_PAUSED {
+    return 0, constants.ERR_GAME_NOT_PAUSED
+  }
+  paused := gs.clock.Since(gs.PausedAt)
+  gs.Status = constants.GAME_STATUS_RUNNING
+  gs.PausedDuration += paused
+  gs.PausedAt = time.Time{}
+  return paused, nil
+}
+
+// Elapsed is how long the game has been played, not counting any time paused
+func (gs *GameState) Elapsed() time.Duration {
+  now := gs.clock.Now()
+  if gs.Paused() {
+    now = gs.PausedAt
+  }
+  return now.Sub(gs.StartedAt) - gs.PausedDuration
 }
 
 func (gs *GameState) SetWinner(team string, score int) {
@@ -86,14 +308,323 @@ func (gs *GameState) SetWinner(team string, score int) {
     gs.gamelock.Lock()
     defer gs.gamelock.Unlock()
     gs.Winner = team
+    gs.Winners = []string{team}
     gs.Highscore = score
   } // we should probably return error in else case
 }
 
+// SetWinners records every team that finished on the high score, which is a draw if there is more than one
+func (gs *GameState) SetWinners(teams []string, score int) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  gs.Winners = teams
+  gs.Winner = strings.Join(teams, constants.COMMA)
+  gs.Highscore = score
+  if len(teams) > 1 {
+    gs.Phase = constants.GAME_PHASE_DRAW
+  }
+}
+
+// Leaders returns the teams on the high score (sorted) and the score itself
+func (gs *GameState) Leaders() ([]string, int) {
+  leaders := []string{}
+  highscore := 0
+
+  for team, points := range gs.Scoreboard {
+    if len(leaders) == 0 || points > highscore {
+      leaders = []string{team}
+      highscore = points
+    } else if points == highscore {
+      leaders = append(leaders, team)
+    }
+  }
+
+  slices.Sort(leaders)
+  return leaders, highscore
+}
+
+// StartTieBreak moves a tied game into overtime or sudden death, adding the extra time to the game
+func (gs *GameState) StartTieBreak(phase string, tied []string, score int, extra time.Duration) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  gs.Phase = phase
+  gs.TiedTeams = tied
+  gs.TieScore = score
+  gs.GameDuration = gs.Elapsed() + extra
+}
+
+// InRegulation is true until the game goes to a tie break
+func (gs *GameState) InRegulation() bool {
+  return gs.Phase == constants.GAME_PHASE_REGULATION
+}
+
+// SuddenDeathWinner returns the tied team that scored first in sudden death, if exactly one has
+func (gs *GameState) SuddenDeathWinner() string {
+  if gs.Phase != constants.GAME_PHASE_SUDDEN_DEATH {
+    return ""
+  }
+
+  scored := []string{}
+  for _, team := range gs.TiedTeams {
+    if gs.Scoreboard[team] > gs.TieScore {
+      scored = append(scored, team)
+    }
+  }
+
+  if len(scored) == 1 {
+    return scored[0]
+  }
+  return "" // nobody, or more than one team, scored since the last check
+}
+
+func (gs *GameState) SetHill(node string) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  gs.Hill = node
+}
+
+// LightTarget tracks the newly lit target, recording the previous one as missed if it was never hit,
+// a random sensor is left for the node to pick so any of its sensors can be the target
+func (gs *GameState) LightTarget(node, sensor, team string) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  if gs.Target != nil {
+    gs.Target.Missed = true
+    gs.Reactions = append(gs.Reactions, *gs.Target)
+  }
+  if sensor == constants.RANDOM_SENSOR_ID {
+    sensor = ""
+  }
+  gs.Target = &TargetReaction{Node: node, Sensor: sensor, Team: team, LitAt: gs.clock.Now()}
+}
+
+// HitTarget records the reaction time of the current target, returning false if the hit was not on it
+func (gs *GameState) HitTarget(node, sensor, team string, reactionms int64) bool {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  if gs.Target == nil || gs.Target.Node != node {
+    return false
+  }
+  if gs.Target.Sensor != "" && gs.Target.Sensor != sensor {
+    return false
+  }
+  gs.Target.Sensor = sensor
+  gs.Target.Team = team
+  gs.Target.ReactionMs = reactionms
+  gs.Reactions = append(gs.Reactions, *gs.Target)
+  gs.Target = nil
+
+  if trial := gs.currentTrial(); trial != nil {
+    trial.SplitsMs = append(trial.SplitsMs, reactionms)
+  }
+
+  if gs.Drill != nil {
+    gs.Drill.Hits += 1
+    gs.Drill.SplitsMs = append(gs.Drill.SplitsMs, reactionms)
+    gs.Drill.TotalMs += reactionms
+    gs.Drill.Accuracy = float64(gs.Drill.Hits) / float64(gs.Drill.Hits + gs.Drill.Misses)
+  }
+  return true
+}
+
+// MissTarget counts a hit on the wrong sensor against the drill's accuracy
+func (gs *GameState) MissTarget() {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  if trial := gs.currentTrial(); trial != nil {
+    trial.Misses += 1
+    trial.PenaltyMs += int64(gs.config.PenaltySeconds) * 1000
+  }
+  if gs.Drill != nil {
+    gs.Drill.Misses += 1
+    gs.Drill.Accuracy = float64(gs.Drill.Hits) / float64(gs.Drill.Hits + gs.Drill.Misses)
+  }
+}
+
+func (gs *GameState) AddTarget(node, sensorid string) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  key := strings.Join([]string{node, sensorid}, constants.SPLIT)
+  gs.Targets[key] = &TargetState{Node: node, Sensor: sensorid, Health: gs.config.TargetHealth}
+}
+
+// DamageTarget takes one health from a live target, eliminating it at zero, and returns a copy of it
+func (gs *GameState) DamageTarget(node, sensorid, team string) *TargetState {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  target, ok := gs.Targets[strings.Join([]string{node, sensorid}, constants.SPLIT)]
+  if !ok || target.Eliminated {
+    return nil
+  }
+
+  target.Hits += 1
+  target.Health -= 1
+  if target.Health <= 0 {
+    target.Health = 0
+    target.Eliminated = true
+    target.EliminatedBy = team
+    target.EliminatedAt = gs.clock.Now()
+  }
+
+  t := *target
+  return &t
+}
+
+// AllTargetsDown is true once every target has been eliminated (and there were targets to begin with)
+func (gs *GameState) AllTargetsDown() bool {
+  if len(gs.Targets) == 0 {
+    return false
+  }
+
+  for _, target := range gs.Targets {
+    if !target.Eliminated {
+      return false
+    }
+  }
+
+  return true
+}
+
+func (gs *GameState) AddBonus(team string, points int) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  gs.Bonus[team] += points
+  gs.Scoreboard[team] += points
+}
+
+// CaptureTarget switches a sensor over to a team, adding it to the sensor's ownership timeline
+func (gs *GameState) CaptureTarget(node, sensorid, team string) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  key := strings.Join([]string{node, sensorid}, constants.SPLIT)
+  gs.Ownership[key] = append(gs.Ownership[key], OwnershipChange{Team: team, At: gs.clock.Now()})
+}
+
+// Owner returns the team currently holding a sensor (by its node:sensor key)
+func (gs *GameState) Owner(key string) string {
+  changes := gs.Ownership[key]
+  if len(changes) == 0 {
+    return ""
+  }
+  return changes[len(changes)-1].Team
+}
+
+// AccrueHoldPoints awards each team a point for every whole second it has held each sensor
+func (gs *GameState) AccrueHoldPoints() {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  now := gs.clock.Now()
+  if gs.Lastaccrual.IsZero() {
+    gs.Lastaccrual = now
+    return
+  }
+
+  secs := int(now.Sub(gs.Lastaccrual) / time.Second)
+  if secs < 1 {
+    return
+  }
+  gs.Lastaccrual = gs.Lastaccrual.Add(time.Duration(secs) * time.Second)
+
+  for key, _ := range gs.Ownership {
+    if owner := gs.Owner(key); owner != "" {
+      gs.Bonus[owner] += secs
+    }
+  }
+}
+
+// TimeTrial is true in games where teams run a course against the clock
+func (gs *GameState) TimeTrial() bool {
+  return len(gs.Course) > 0
+}
+
+func (gs *GameState) StartTrial(team string) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  gs.Trials = append(gs.Trials, &TrialRun{Team: team, StartedAt: gs.clock.Now(), SplitsMs: []int64{}})
+}
+
+// FinishTrial stops the clock on the team's run, returning a copy of it
+func (gs *GameState) FinishTrial(team string) *TrialRun {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  trial := gs.currentTrial()
+  if trial == nil || trial.Team != team {
+    return nil
+  }
+
+  trial.Finished = true
+  trial.FinishedAt = gs.clock.Now()
+  trial.TimeMs = trial.PenaltyMs
+  for _, split := range trial.SplitsMs {
+    trial.TimeMs += split
+  }
+
+  t := *trial
+  return &t
+}
+
+// currentTrial is the run in progress, if any (the caller must hold the lock)
+func (gs *GameState) currentTrial() *TrialRun {
+  if len(gs.Trials) == 0 || gs.Trials[len(gs.Trials)-1].Finished {
+    return nil
+  }
+  return gs.Trials[len(gs.Trials)-1]
+}
+
+// TrialsComplete is true once every team has finished the course
+func (gs *GameState) TrialsComplete() bool {
+  if !gs.TimeTrial() {
+    return false
+  }
+
+  for _, team := range gs.Teams {
+    if !slices.ContainsFunc(gs.Trials, func(t *TrialRun) bool { return t.Team == team && t.Finished }) {
+      return false
+    }
+  }
+  return true
+}
+
+// Fastest returns the team(s) with the best finished time and the time itself
+func (gs *GameState) Fastest() ([]string, int64) {
+  fastest := []string{}
+  best := int64(0)
+
+  for _, trial := range gs.Trials {
+    if !trial.Finished {
+      continue
+    }
+    if len(fastest) == 0 || trial.TimeMs < best {
+      fastest = []string{trial.Team}
+      best = trial.TimeMs
+    } else if trial.TimeMs == best {
+      fastest = append(fastest, trial.Team)
+    }
+  }
+
+  slices.Sort(fastest)
+  return fastest, best
+}
+
+func (gs *GameState) SetBestTime(ms int64) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  gs.BestTimeMs = ms
+}
+
+// DrillComplete is true once every target in a drill has been hit
+func (gs *GameState) DrillComplete() bool {
+  return gs.Drill != nil && gs.Drill.Hits >= gs.Drill.Targets
+}
+
 func (gs *GameState) Running() bool {
   return gs.Status == constants.GAME_STATUS_RUNNING
 }
 
+func (gs *GameState) Paused() bool {
+  return gs.Status == constants.GAME_STATUS_PAUSED
+}
+
 func (gs *GameState) AddNode(node string) {
   gs.gamelock.Lock()
   defer gs.gamelock.Unlock()
@@ -113,12 +644,143 @@ func (gs *GameState) AddTeam(team string) {
 func (gs *GameState) SetBoards(sb, nb map[string]int) {
   gs.gamelock.Lock()
   defer gs.gamelock.Unlock()
+  for team, points := range gs.Bonus {
+    sb[team] += points // bonus points are awarded by the engine, not counted by nodes
+  }
   gs.Scoreboard = sb
   gs.Nodeboard = nb
-  gs.Lastcheck = time.Now()
+  gs.Lastcheck = gs.clock.Now()
   gs.checking = false
 }
 
+// AddHit keeps the scores live with a hit pushed by a node, the scoreboard poll reconciles them (see Drift),
+// returning false if the node already pushed the hit (by its run and journal sequence number) so it is not counted twice
+func (gs *GameState) AddHit(node, run string, seq uint64, team string, hits, points int) bool {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+
+  if gs.Reported == nil {
+    gs.Reported = map[string]*ReportedHits{} // checkpoints from before hits were journaled
+  }
+
+  // a node that lost its journal starts counting again, under a new run
+  key := strings.Join([]string{node, run}, constants.SPLIT)
+  if _, ok := gs.Reported[key]; !ok {
+    gs.Reported[key] = &ReportedHits{Upto: 0, Above: []uint64{}}
+  }
+  if !gs.Reported[key].Add(seq) {
+    return false
+  }
+
+  if slices.Contains(gs.Nodes, node) {
+    gs.Nodeboard[node] += hits
+  }
+
+  if !slices.Contains(gs.Teams, team) {
+    return true // not a team (i.e. a sensor color or a capture) so there is nothing to score
+  }
+
+  gs.Hitboard[team] += hits
+
+  gs.RawScoreboard[team] += points
+  for handicapped := range gs.config.Handicaps {
+    if _, ok := gs.RawScoreboard[handicapped]; !ok && slices.Contains(gs.Teams, handicapped) {
+      gs.RawScoreboard[handicapped] = 0
+    }
+  }
+
+  sb := gs.config.Handicap(gs.RawScoreboard)
+  for team, points := range gs.Bonus {
+    sb[team] += points
+  }
+  gs.Scoreboard = sb
+  return true
+}
+
+// Drift lists where the pushed scores differ from a poll of the node scoreboards (before bonus points),
+// a hit still on its way to the controller shows up here too
+func (gs *GameState) Drift(sb, nb map[string]int) []string {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  drift := []string{}
+
+  polled := map[string]int{}
+  for team, points := range sb {
+    polled[team] = points
+  }
+  for team, points := range gs.Bonus {
+    polled[team] += points
+  }
+
+  for _, team := range gs.Teams {
+    if gs.Scoreboard[team] != polled[team] {
+      drift = append(drift, fmt.Sprintf("%s pushed %d polled %d", team, gs.Scoreboard[team], polled[team]))
+    }
+  }
+
+  for _, node := range gs.Nodes {
+    if gs.Nodeboard[node] != nb[node] {
+      drift = append(drift, fmt.Sprintf("%s pushed %d polled %d", node, gs.Nodeboard[node], nb[node]))
+    }
+  }
+
+  return drift
+}
+
+// CheckInterval is how often the scores are polled, which is much sooner in sudden death
+func (gs *GameState) CheckInterval() time.Duration {
+  if gs.Phase == constants.GAME_PHASE_SUDDEN_DEATH {
+    return 1 * time.Second
+  }
+  return 10 * time.Second
+}
+
+func (gs *GameState) SetHitboard(hb map[string]int) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  gs.Hitboard = hb
+}
+
+// AddStreak counts a pushed hit towards its team's streak, returning how many streak bonuses it earned,
+// a hit by another team (or on a civilian) ends the streak
+func (gs *GameState) AddStreak(team string, hits, points int) int {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+
+  if gs.config.StreakHits <= 0 || !slices.Contains(gs.Teams, team) {
+    return 0
+  }
+
+  if points < 0 {
+    gs.Streak = TeamStreak{}
+    return 0
+  }
+
+  window, err := time.ParseDuration(gs.config.StreakWindow)
+  if err != nil {
+    return 0
+  }
+
+  now := gs.clock.Now()
+  before := 0
+  if gs.Streak.Team == team && now.Sub(gs.Streak.LastHitAt) <= window {
+    before = gs.Streak.Hits
+  }
+
+  gs.Streak = TeamStreak{Team: team, Hits: before + hits, LastHitAt: now}
+  if gs.Streak.Hits > gs.BestStreaks[team] {
+    gs.BestStreaks[team] = gs.Streak.Hits
+  }
+
+  return gs.Streak.Hits / gs.config.StreakHits - before / gs.config.StreakHits
+}
+
+func (gs *GameState) SetRawScoreboard(raw map[string]int) {
+  gs.gamelock.Lock()
+  defer gs.gamelock.Unlock()
+  gs.RawScoreboard = raw
+}
+
 func (gs *GameState) Checking() bool {
   return gs.checking
 }
@@ -133,9 +795,14 @@ func (gs *GameState) TeamList() string {
   return strings.Join(gs.Teams, constants.COMMA)
 }
 
+// Rand is the game's own random source, seeded so the game can be replayed
+func (gs *GameState) Rand() *rand.Rand {
+  return gs.rand
+}
+
 func (gs *GameState) RandomTeam() string {
   if len(gs.Teams) > 0 {
-    return gs.Teams[rand.Intn(len(gs.Teams))]
+    return gs.Teams[gs.rand.Intn(len(gs.Teams))]
   } else {
     return ""
   }
@@ -143,7 +810,7 @@ func (gs *GameState) RandomTeam() string {
 
 func (gs *GameState) RandomColor() string {
   if len(gs.Colors) > 0 {
-    return gs.Colors[rand.Intn(len(gs.Colors))]
+    return gs.Colors[gs.rand.Intn(len(gs.Colors))]
   } else {
     return ""
   }
@@ -151,17 +818,18 @@ func (gs *GameState) RandomColor() string {
 
 func (gs *GameState) RandomNode() string {
   if len(gs.Nodes) > 0 {
-    return gs.Nodes[rand.Intn(len(gs.Nodes))]
+    return gs.Nodes[gs.rand.Intn(len(gs.Nodes))]
   } else {
     return ""
   }
 }
 
-func (gs *GameState) Winners() []string {
+func (gs *GameState) TeamsAtWinningScore() []string {
   winningteams := []string{}
 
   for team, points := range gs.Scoreboard {
-    if points >= gs.config.WinningScore {
+    // no winning score, only time (or the game) ends it
+    if ws := gs.config.WinningScoreFor(team); ws > 0 && points >= ws {
       winningteams = append(winningteams, team)
     }
   }
@@ -201,6 +869,18 @@ func (gs *GameState) ValidateNodes() error {
     }
   }
 
+  // a course is only set (even if empty) by game modes that run one
+  if gs.config.Course != nil && len(gs.config.Course) == 0 {
+    return constants.ERR_NO_COURSE
+  }
+
+  for _, stop := range gs.config.Course {
+    parts := strings.Split(stop, constants.SPLIT)
+    if len(parts) != 2 || !slices.Contains(gs.Nodes, parts[0]) {
+      return constants.ERR_INVALID_COURSE
+    }
+  }
+
   return nil
 }
 
@@ -209,7 +889,7 @@ func (gs *GameState) TimeExpired() bool {
     return true
   }
 
-  if time.Now().After(gs.StartedAt.Add(gs.GameDuration)) {
+  if gs.Elapsed() > gs.GameDuration {
     return true
   }
 
@@ -221,23 +901,69 @@ func (gs *GameState) WinningScoreReached() bool {
     return true
   }
 
-  if len(gs.Winners()) > 0 {
+  if len(gs.TeamsAtWinningScore()) > 0 {
     return true
   }
 
   return false
 }
 
+// GameOver returns the reason the game should end, or an empty string if it should go on
+func (gs *GameState) GameOver() string {
+  if gs.SuddenDeathWinner() != "" {
+    return "a team scored in sudden death"
+  }
+
+  if !gs.InRegulation() && gs.TimeExpired() {
+    return "overtime expired"
+  }
+
+  if gs.config.EndsOn(constants.END_ON_TIME) && gs.TimeExpired() {
+    return "game time expired"
+  }
+
+  if gs.AllTargetsDown() {
+    return "all targets have been eliminated"
+  }
+
+  if gs.DrillComplete() {
+    return "all drill targets have been hit"
+  }
+
+  if gs.TrialsComplete() {
+    return "every team has run the course"
+  }
+
+  // tied teams may already be past the winning score, so only time ends a tie break
+  if gs.InRegulation() && gs.config.EndsOn(constants.END_ON_SCORE) && gs.WinningScoreReached() {
+    return "the winning score has been reached"
+  }
+
+  return ""
+}
+
 func (gs *GameState) GameStatus() string {
-  timeleft := gs.GameDuration - time.Since(gs.StartedAt)
+  timeleft := gs.GameDuration - gs.Elapsed()
   if timeleft < 0 {
     timeleft = 0
   }
   s := "\n\n###################################\n"
   s += fmt.Sprintf("Game Status: (%s)\n", gs.Status)
+  if !gs.InRegulation() {
+    s += fmt.Sprintf("Game Phase: %s (tied at %d: %s)\n", gs.Phase, gs.TieScore, strings.Join(gs.TiedTeams, constants.COMMA))
+  }
   s += fmt.Sprintf("Time Remaining: %s\n", timeleft)
-  s += fmt.Sprintf("Scoreboard: \n%s\n\n", gs.Scoreboard)
-  s += fmt.Sprintf("Nodeboard: \n%s\n\n", gs.Nodeboard)
+  s += fmt.Sprintf("Scoreboard: \n%v\n\n", gs.Scoreboard)
+  s += fmt.Sprintf("Nodeboard: \n%v\n\n", gs.Nodeboard)
+  if len(gs.Targets) > 0 {
+    down := 0
+    for _, target := range gs.Targets {
+      if target.Eliminated {
+        down += 1
+      }
+    }
+    s += fmt.Sprintf("Targets Remaining: %d/%d\n\n", len(gs.Targets) - down, len(gs.Targets))
+  }
   return s
 }
 
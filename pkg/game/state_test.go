@@ -0,0 +1,281 @@
+package game
+
+import (
+  "log"
+  "time"
+  "slices"
+  "testing"
+  "github.com/taemon1337/arena-nerf/pkg/common"
+  "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+func newTestGameState(clock common.Clock, seed int64) *GameState {
+  cfg := config.NewConfig(log.Default())
+  cfg.Nodes = []string{"node1", "node2", "node3"}
+  cfg.Colors = []string{"red", "blue", "green", "yellow"}
+  cfg.GameLength = "1m"
+
+  gc := NewGameConfig(cfg)
+  gc.Clock = clock
+  gc.Seed = seed
+  return NewGameState(gc)
+}
+
+func TestTimeExpired(t *testing.T) {
+  clock := common.NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+  gs := newTestGameState(clock, 1)
+  if err := gs.GameSetup(); err != nil {
+    t.Fatalf("cannot set game up: %s", err)
+  }
+
+  clock.Advance(59 * time.Second)
+  if gs.TimeExpired() {
+    t.Fatalf("time expired after %s of a 1m game", gs.Elapsed())
+  }
+
+  // time paused does not count
+  if err := gs.Pause(); err != nil {
+    t.Fatalf("cannot pause game: %s", err)
+  }
+  clock.Advance(10 * time.Minute)
+  if gs.TimeExpired() {
+    t.Fatalf("time expired while paused")
+  }
+  if _, err := gs.Resume(); err != nil {
+    t.Fatalf("cannot resume game: %s", err)
+  }
+
+  clock.Advance(2 * time.Second)
+  if !gs.TimeExpired() {
+    t.Fatalf("time not expired after %s of a 1m game", gs.Elapsed())
+  }
+}
+
+func TestTimeExpiredWhenEnded(t *testing.T) {
+  gs := newTestGameState(common.NewManualClock(time.Now()), 1)
+  if err := gs.GameSetup(); err != nil {
+    t.Fatalf("cannot set game up: %s", err)
+  }
+
+  gs.EndGame()
+  if !gs.TimeExpired() {
+    t.Fatalf("time not expired once the game ended")
+  }
+}
+
+func TestRandomNode(t *testing.T) {
+  clock := common.NewManualClock(time.Now())
+  a := newTestGameState(clock, 42)
+  b := newTestGameState(clock, 42)
+
+  seen := map[string]bool{}
+  for i := 0; i < 50; i++ {
+    node := a.RandomNode()
+    if other := b.RandomNode(); node != other {
+      t.Fatalf("pick %d differs with the same seed: %s != %s", i, node, other)
+    }
+    seen[node] = true
+  }
+
+  for node, _ := range seen {
+    if node != "node1" && node != "node2" && node != "node3" {
+      t.Fatalf("picked unknown node %s", node)
+    }
+  }
+  if len(seen) != 3 {
+    t.Fatalf("50 picks only picked %d of 3 nodes", len(seen))
+  }
+}
+
+func TestRandomColor(t *testing.T) {
+  clock := common.NewManualClock(time.Now())
+  a := newTestGameState(clock, 7)
+  b := newTestGameState(clock, 7)
+
+  for i := 0; i < 50; i++ {
+    if color, other := a.RandomColor(), b.RandomColor(); color != other {
+      t.Fatalf("pick %d differs with the same seed: %s != %s", i, color, other)
+    }
+  }
+
+  empty := newTestGameState(clock, 7)
+  empty.Colors = []string{}
+  if color := empty.RandomColor(); color != "" {
+    t.Fatalf("picked %s without any colors", color)
+  }
+}
+
+func TestHitTarget(t *testing.T) {
+  tests := []struct {
+    name    string
+    lit     string  // sensor lit on node1
+    node    string
+    sensor  string
+    want    bool
+  }{
+    {"lit sensor", "s1", "node1", "s1", true},
+    {"other sensor on the node", "s1", "node1", "s2", false},
+    {"other node", "s1", "node2", "s1", false},
+    {"node picked the sensor", constants.RANDOM_SENSOR_ID, "node1", "s2", true},
+    {"node picked, other node", constants.RANDOM_SENSOR_ID, "node2", "s2", false},
+  }
+
+  for _, tt := range tests {
+    clock := common.NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+    gs := newTestGameState(clock, 1)
+    gs.LightTarget("node1", tt.lit, "red")
+    clock.Advance(500 * time.Millisecond)
+
+    if got := gs.HitTarget(tt.node, tt.sensor, "red", 500); got != tt.want {
+      t.Errorf("%s: hit on %s:%s counted %v, want %v", tt.name, tt.node, tt.sensor, got, tt.want)
+    }
+    if tt.want && gs.Target != nil {
+      t.Errorf("%s: target still lit after it was hit", tt.name)
+    }
+    if !tt.want && gs.Target == nil {
+      t.Errorf("%s: target cleared by a hit elsewhere", tt.name)
+    }
+  }
+}
+
+func TestEndGameOnce(t *testing.T) {
+  clock := common.NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+  gs := newTestGameState(clock, 1)
+  if gs.EndGame() {
+    t.Fatalf("ended a game that never started")
+  }
+
+  if err := gs.GameSetup(); err != nil {
+    t.Fatalf("cannot set game up: %s", err)
+  }
+  if err := gs.Pause(); err != nil {
+    t.Fatalf("cannot pause game: %s", err)
+  }
+
+  ended := 0
+  done := make(chan bool)
+  for i := 0; i < 10; i++ {
+    go func() { done <- gs.EndGame() }()
+  }
+  for i := 0; i < 10; i++ {
+    if <-done {
+      ended += 1
+    }
+  }
+
+  if ended != 1 {
+    t.Fatalf("game ended %d times, want once", ended)
+  }
+  if gs.Status != constants.GAME_STATUS_ENDED {
+    t.Fatalf("game status %s after ending it", gs.Status)
+  }
+}
+
+func TestReportedHitsAdd(t *testing.T) {
+  tests := []struct {
+    name    string
+    seqs    []uint64
+    counted []bool
+    upto    uint64
+    above   []uint64
+  }{
+    {"in order", []uint64{1, 2, 3}, []bool{true, true, true}, 3, []uint64{}},
+    {"duplicate", []uint64{1, 2, 2, 1}, []bool{true, true, false, false}, 2, []uint64{}},
+    {"out of order", []uint64{1, 3, 4, 2}, []bool{true, true, true, true}, 4, []uint64{}},
+    {"gap", []uint64{1, 3, 5}, []bool{true, true, true}, 1, []uint64{3, 5}},
+    {"duplicate above a gap", []uint64{3, 3, 1}, []bool{true, false, true}, 1, []uint64{3}},
+  }
+
+  for _, tt := range tests {
+    r := &ReportedHits{Upto: 0, Above: []uint64{}}
+    for i, seq := range tt.seqs {
+      if got := r.Add(seq); got != tt.counted[i] {
+        t.Errorf("%s: hit %d counted %v, want %v", tt.name, seq, got, tt.counted[i])
+      }
+    }
+    if r.Upto != tt.upto || !slices.Equal(r.Above, tt.above) {
+      t.Errorf("%s: reported up to %d and %v, want %d and %v", tt.name, r.Upto, r.Above, tt.upto, tt.above)
+    }
+  }
+}
+
+func TestAddHit(t *testing.T) {
+  clock := common.NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+  gs := newTestGameState(clock, 1)
+  gs.Teams = []string{"red", "blue"}
+
+  hits := []struct {
+    node    string
+    run     string
+    seq     uint64
+    counted bool
+  }{
+    {"node1", "run1", 1, true},
+    {"node1", "run1", 1, false},  // a retry the controller already counted
+    {"node1", "run1", 3, true},   // out of order
+    {"node1", "run1", 2, true},
+    {"node1", "run1", 3, false},
+    {"node2", "run1", 1, true},   // same run name, another node
+    {"node1", "run2", 1, true},   // the node lost its journal and counts again
+  }
+
+  for _, hit := range hits {
+    if got := gs.AddHit(hit.node, hit.run, hit.seq, "red", 1, 2); got != hit.counted {
+      t.Errorf("hit %s:%s:%d counted %v, want %v", hit.node, hit.run, hit.seq, got, hit.counted)
+    }
+  }
+
+  if gs.Hitboard["red"] != 5 || gs.Scoreboard["red"] != 10 {
+    t.Errorf("red has %d hits and %d points, want 5 and 10", gs.Hitboard["red"], gs.Scoreboard["red"])
+  }
+  if gs.Nodeboard["node1"] != 4 || gs.Nodeboard["node2"] != 1 {
+    t.Errorf("nodeboard %v, want node1 4 and node2 1", gs.Nodeboard)
+  }
+}
+
+func TestAddStreak(t *testing.T) {
+  clock := common.NewManualClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+  gs := newTestGameState(clock, 1)
+  gs.Teams = []string{"red", "blue"}
+  gs.config.StreakHits = 3
+  gs.config.StreakWindow = "5s"
+
+  tests := []struct {
+    name    string
+    after   time.Duration   // since the last hit
+    team    string
+    hits    int
+    points  int
+    streaks int             // bonuses earned
+    streak  int             // hits in a row after this one
+  }{
+    {"first", 0, "red", 1, 1, 0, 1},
+    {"second", 2 * time.Second, "red", 1, 1, 0, 2},
+    {"third earns a bonus", 2 * time.Second, "red", 1, 1, 1, 3},
+    {"fourth", 5 * time.Second, "red", 1, 1, 0, 4},
+    {"too slow starts over", 6 * time.Second, "red", 1, 1, 0, 1},
+    {"another team ends it", 1 * time.Second, "blue", 1, 1, 0, 1},
+    {"blue", 1 * time.Second, "blue", 1, 1, 0, 2},
+    {"a civilian ends it", 1 * time.Second, "blue", 1, -5, 0, 0},
+    {"blue again", 1 * time.Second, "blue", 1, 1, 0, 1},
+    {"a multi hit past two bonuses", 1 * time.Second, "blue", 6, 6, 2, 7},
+  }
+
+  for _, tt := range tests {
+    clock.Advance(tt.after)
+    if got := gs.AddStreak(tt.team, tt.hits, tt.points); got != tt.streaks || gs.Streak.Hits != tt.streak {
+      t.Errorf("%s: %d bonuses and a streak of %d, want %d and %d", tt.name, got, gs.Streak.Hits, tt.streaks, tt.streak)
+    }
+  }
+
+  if gs.BestStreaks["red"] != 4 || gs.BestStreaks["blue"] != 7 {
+    t.Errorf("best streaks %v, want red 4 and blue 7", gs.BestStreaks)
+  }
+
+  // off unless the streak flags are set
+  gs.config.StreakHits = 0
+  if got := gs.AddStreak("red", 10, 10); got != 0 {
+    t.Errorf("%d bonuses with streaks off", got)
+  }
+}
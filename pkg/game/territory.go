@@ -0,0 +1,37 @@
+package game
+
+import (
+  "log"
+  "time"
+  "context"
+
+  "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// GameTerritory is capture and hold, a hit switches a sensor over to the next team's color
+// (sensors cannot tell who fired, so teams keep hitting until it shows theirs), a team hit captures
+// it for that team, and teams earn a point for every second they hold each sensor
+type GameTerritory struct {
+  GameBase
+}
+
+func NewGameTerritory(id, mode string, cfg *config.Config, gamechan *GameChannel, logger *log.Logger) *GameTerritory {
+  return &GameTerritory{
+    GameBase:       NewGameBase(id, mode, cfg, gamechan, log.New(logger.Writer(), "[TERRITORY]: ", logger.Flags())),
+  }
+}
+
+func (g *GameTerritory) Start(ctx context.Context) error {
+  g.Printf("starting game %s", g)
+  g.Begin("starting territory control!")
+
+  timer := g.NewTimer(1 * time.Second, true, func() {
+    g.Request(constants.TERRITORY_TICK, "awarding held sensors")
+  })
+  return g.Run(ctx, timer, nil)
+}
+
+func (g *GameTerritory) String() string {
+  return constants.GAME_MODE_TERRITORY
+}
@@ -0,0 +1,99 @@
+package game
+
+import (
+  "log"
+  "time"
+  "context"
+  "strings"
+
+  "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// GameTimeTrial has each team run the same ordered course of targets, one team after another,
+// out of order hits add penalty seconds and the fastest time wins
+type GameTimeTrial struct {
+  GameBase
+  course        []string
+}
+
+func NewGameTimeTrial(id, mode string, cfg *config.Config, gamechan *GameChannel, logger *log.Logger) *GameTimeTrial {
+  course := []string{}
+  for _, stop := range strings.Split(cfg.Course, constants.COMMA) {
+    if stop = strings.TrimSpace(stop); stop != "" {
+      course = append(course, stop)
+    }
+  }
+
+  return &GameTimeTrial{
+    GameBase:       NewGameBase(id, mode, cfg, gamechan, log.New(logger.Writer(), "[TIMETRIAL]: ", logger.Flags())),
+    course:         course,
+  }
+}
+
+func (g *GameTimeTrial) Configure(gc *GameConfig) {
+  gc.MinTeamCount = 1
+  gc.WinningScore = 0 // the trial is over once every team has run the course (or time runs out)
+  gc.EndConditions = []string{constants.END_ON_TIME}
+  gc.Course = g.course
+}
+
+func (g *GameTimeTrial) Start(ctx context.Context) error {
+  g.Printf("starting game %s - %d targets for %s", g, len(g.course), strings.Join(g.conf.Teams, constants.COMMA))
+  g.Begin("starting time trial!")
+
+  roundbreak, err := time.ParseDuration(g.conf.RoundBreak)
+  if err != nil {
+    g.Printf("invalid round break '%s', starting teams right away", g.conf.RoundBreak)
+    roundbreak = 0
+  }
+
+  teams := g.conf.Teams
+  team := 0
+  stop := 0
+  g.StartRun(teams[team])
+
+  // set while waiting between teams
+  next := g.NewStoppedTimer(roundbreak, false, func() {
+    g.StartRun(teams[team])
+  })
+
+  return g.Run(ctx, next, func(evt GameEvent) {
+    switch evt.Event {
+      case constants.TARGET_HIT:
+        g.Printf("target hit: %s", string(evt.Payload))
+        stop += 1
+        if stop < len(g.course) {
+          g.LightStop(stop, teams[team])
+          return
+        }
+
+        g.Request(constants.TRIAL_FINISH, teams[team])
+        team += 1
+        stop = 0
+        if team < len(teams) {
+          g.Printf("%s is up next in %s", teams[team], roundbreak)
+          next.Reset()
+        }
+      default:
+        g.Printf("unrecognized time trial event: %s", evt.Event)
+    }
+  })
+}
+
+// StartRun starts the clock for a team and lights the first target on the course
+func (g *GameTimeTrial) StartRun(team string) {
+  g.Printf("%s is running the course", team)
+  g.Request(constants.TRIAL_START, team)
+  g.LightStop(0, team)
+}
+
+// LightStop asks the engine to light a target on the course in the team's color
+func (g *GameTimeTrial) LightStop(stop int, team string) {
+  pay := strings.Join([]string{g.course[stop], team}, constants.SPLIT)
+  g.Request(constants.SET_TARGET, pay)
+}
+
+func (g *GameTimeTrial) String() string {
+  return constants.GAME_MODE_TIME_TRIAL
+}
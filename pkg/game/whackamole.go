@@ -0,0 +1,57 @@
+package game
+
+import (
+  "log"
+  "time"
+  "context"
+
+  "github.com/taemon1337/arena-nerf/pkg/config"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+)
+
+// GameWhackAMole lights one random target at a time in a random team's color, that team
+// scores by hitting it before the timeout, otherwise the target moves somewhere else
+type GameWhackAMole struct {
+  GameBase
+  timeout       time.Duration
+}
+
+func NewGameWhackAMole(id, mode string, cfg *config.Config, gamechan *GameChannel, logger *log.Logger) *GameWhackAMole {
+  logger = log.New(logger.Writer(), "[WHACKAMOLE]: ", logger.Flags())
+
+  timeout, err := time.ParseDuration(cfg.TargetTimeout)
+  if err != nil || timeout <= 0 {
+    logger.Printf("invalid target timeout '%s', using %s", cfg.TargetTimeout, constants.DEFAULT_TARGET_TIMEOUT)
+    timeout = constants.DEFAULT_TARGET_TIMEOUT
+  }
+
+  return &GameWhackAMole{
+    GameBase:       NewGameBase(id, mode, cfg, gamechan, logger),
+    timeout:        timeout,
+  }
+}
+
+func (g *GameWhackAMole) Start(ctx context.Context) error {
+  g.Printf("starting game %s (targets move after %s)", g, g.timeout)
+  g.Begin("starting whack-a-mole!")
+  g.Request(constants.RANDOM_TARGET, "lighting the first target")
+
+  deadline := g.NewTimer(g.timeout, true, func() {
+    g.Request(constants.RANDOM_TARGET, "target timed out")
+  })
+
+  return g.Run(ctx, deadline, func(evt GameEvent) {
+    switch evt.Event {
+      case constants.TARGET_HIT:
+        g.Printf("target hit: %s", string(evt.Payload))
+        g.Request(constants.RANDOM_TARGET, "target was hit")
+        deadline.Reset()
+      default:
+        g.Printf("unrecognized whack-a-mole event: %s", evt.Event)
+    }
+  })
+}
+
+func (g *GameWhackAMole) String() string {
+  return constants.GAME_MODE_WHACK_A_MOLE
+}
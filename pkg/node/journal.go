@@ -0,0 +1,165 @@
+package node
+
+import (
+  "os"
+  "sync"
+  "bufio"
+  "slices"
+  "path/filepath"
+  "encoding/json"
+  "github.com/google/uuid"
+  "github.com/taemon1337/arena-nerf/pkg/game"
+)
+
+// Journal appends the record of every hit of the current game to a file, so the hits survive a restart of the node
+// and can be pushed to the controller again after a restart or partition
+type Journal struct {
+  path          string              // empty keeps the journal in memory only
+  Run           string              `yaml:"run" json:"run"` // new every time the node starts, so a lost journal cannot reuse a counted seq
+  GameId        string              `yaml:"game_id" json:"game_id"`
+  Seq           uint64              `yaml:"seq" json:"seq"`
+  Entries       []game.HitRecord    `yaml:"entries" json:"entries"`
+  journallock   *sync.Mutex         `yaml:"-" json:"-"`
+}
+
+func NewJournal(dir, name string) *Journal {
+  path := ""
+  if dir != "" {
+    path = filepath.Join(dir, name + ".jsonl")
+  }
+
+  return &Journal{
+    path:         path,
+    Run:          uuid.New().String(),
+    GameId:       "",
+    Seq:          0,
+    Entries:      []game.HitRecord{},
+    journallock:  &sync.Mutex{},
+  }
+}
+
+// Load reads back the hits of the last game in the journal, a line cut short by a crash is skipped
+func (j *Journal) Load() error {
+  j.journallock.Lock()
+  defer j.journallock.Unlock()
+
+  if j.path == "" {
+    return nil
+  }
+
+  f, err := os.Open(j.path)
+  if os.IsNotExist(err) {
+    return nil
+  }
+  if err != nil {
+    return err
+  }
+  defer f.Close()
+
+  scanner := bufio.NewScanner(f)
+  for scanner.Scan() {
+    var entry game.HitRecord
+    if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+      continue
+    }
+
+    if entry.GameId != j.GameId {
+      j.GameId = entry.GameId
+      j.Entries = []game.HitRecord{}
+    }
+    j.Entries = append(j.Entries, entry)
+    j.Seq = max(j.Seq, entry.Seq)
+  }
+  return scanner.Err()
+}
+
+// Reset starts the journal over for a new game, a resumed game keeps the hits already journaled for it
+func (j *Journal) Reset(gameid string, resume bool) error {
+  j.journallock.Lock()
+  defer j.journallock.Unlock()
+
+  if resume && j.GameId == gameid {
+    return nil
+  }
+  return j.reset(gameid)
+}
+
+func (j *Journal) reset(gameid string) error {
+  j.GameId = gameid
+  j.Seq = 0
+  j.Entries = []game.HitRecord{}
+
+  if j.path == "" {
+    return nil
+  }
+
+  if err := os.MkdirAll(filepath.Dir(j.path), os.ModePerm); err != nil {
+    return err
+  }
+  return os.WriteFile(j.path, []byte{}, os.ModePerm)
+}
+
+// Append numbers the hit record and writes it to disk before it is reported, the entry is returned even when
+// the write fails so the hit still counts while the node stays up
+func (j *Journal) Append(entry game.HitRecord) (game.HitRecord, error) {
+  j.journallock.Lock()
+  defer j.journallock.Unlock()
+
+  if entry.GameId != j.GameId {
+    if err := j.reset(entry.GameId); err != nil {
+      return entry, err
+    }
+  }
+
+  j.Seq += 1
+  entry.Run = j.Run
+  entry.Seq = j.Seq
+  j.Entries = append(j.Entries, entry)
+
+  if j.path == "" {
+    return entry, nil
+  }
+
+  data, err := json.Marshal(entry)
+  if err != nil {
+    return entry, err
+  }
+
+  f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.ModePerm)
+  if err != nil {
+    return entry, err
+  }
+  defer f.Close()
+
+  if _, err := f.Write(append(data, '\n')); err != nil {
+    return entry, err
+  }
+  return entry, f.Sync()
+}
+
+// List is a copy of the journaled hits of the current game
+func (j *Journal) List() []game.HitRecord {
+  j.journallock.Lock()
+  defer j.journallock.Unlock()
+  return slices.Clone(j.Entries)
+}
+
+// Page is the hit records of a game from offset on, at most as many as fit in limit bytes of json
+// (a single record is sent even if it does not fit, the query response then fails and the page is partial)
+func (j *Journal) Page(gameid string, offset, limit int) ([]byte, error) {
+  page := game.HitRecordPage{Records: []game.HitRecord{}}
+  j.journallock.Lock()
+  if gameid == j.GameId && offset < len(j.Entries) {
+    page.Records = slices.Clone(j.Entries[offset:])
+    page.Total = len(j.Entries)
+  }
+  j.journallock.Unlock()
+
+  for {
+    data, err := json.Marshal(page)
+    if err != nil || len(data) <= limit || len(page.Records) <= 1 {
+      return data, err
+    }
+    page.Records = page.Records[:len(page.Records)/2]
+  }
+}
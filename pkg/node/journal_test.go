@@ -0,0 +1,175 @@
+package node
+
+import (
+  "os"
+  "testing"
+  "encoding/json"
+  "github.com/google/uuid"
+  "github.com/taemon1337/arena-nerf/pkg/constants"
+  "github.com/taemon1337/arena-nerf/pkg/game"
+)
+
+func journalSeqs(j *Journal) []uint64 {
+  seqs := []uint64{}
+  for _, entry := range j.List() {
+    seqs = append(seqs, entry.Seq)
+  }
+  return seqs
+}
+
+func TestJournalAppendLoad(t *testing.T) {
+  dir := t.TempDir()
+  j := NewJournal(dir, "node1")
+
+  for _, sensorid := range []string{"one", "two", "three"} {
+    entry, err := j.Append(game.HitRecord{GameId: "game1", SensorId: sensorid, Hits: 1})
+    if err != nil {
+      t.Fatalf("cannot journal hit: %s", err)
+    }
+    if entry.Run != j.Run {
+      t.Fatalf("hit journaled under run %s, not %s", entry.Run, j.Run)
+    }
+  }
+
+  // cut short by a crash in the middle of a write
+  f, err := os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0644)
+  if err != nil {
+    t.Fatalf("cannot open journal: %s", err)
+  }
+  f.WriteString(`{"game_id":"game1","seq":4,"sens`)
+  f.Close()
+
+  // the node restarts
+  loaded := NewJournal(dir, "node1")
+  if err := loaded.Load(); err != nil {
+    t.Fatalf("cannot load journal: %s", err)
+  }
+  if loaded.GameId != "game1" || loaded.Seq != 3 || len(loaded.List()) != 3 {
+    t.Fatalf("loaded game %s up to %d with %v, want game1 up to 3", loaded.GameId, loaded.Seq, journalSeqs(loaded))
+  }
+  if loaded.Run == j.Run {
+    t.Fatalf("restarted node kept run %s", j.Run)
+  }
+
+  // numbering goes on after the restart
+  entry, err := loaded.Append(game.HitRecord{GameId: "game1", SensorId: "four", Hits: 1})
+  if err != nil {
+    t.Fatalf("cannot journal hit: %s", err)
+  }
+  if entry.Seq != 4 {
+    t.Fatalf("hit after restart numbered %d, want 4", entry.Seq)
+  }
+
+  // a hit of the next game starts it over
+  entry, err = loaded.Append(game.HitRecord{GameId: "game2", SensorId: "one", Hits: 1})
+  if err != nil {
+    t.Fatalf("cannot journal hit: %s", err)
+  }
+  if entry.Seq != 1 || len(loaded.List()) != 1 {
+    t.Fatalf("first hit of the next game numbered %d with %v journaled", entry.Seq, journalSeqs(loaded))
+  }
+
+  reloaded := NewJournal(dir, "node1")
+  if err := reloaded.Load(); err != nil {
+    t.Fatalf("cannot load journal: %s", err)
+  }
+  if reloaded.GameId != "game2" || len(reloaded.List()) != 1 {
+    t.Fatalf("loaded game %s with %v, want only the hit of game2", reloaded.GameId, journalSeqs(reloaded))
+  }
+}
+
+func TestJournalReset(t *testing.T) {
+  j := NewJournal("", "node1") // in memory
+  if err := j.Load(); err != nil {
+    t.Fatalf("cannot load in memory journal: %s", err)
+  }
+  j.Append(game.HitRecord{GameId: "game1", SensorId: "one", Hits: 1})
+  j.Append(game.HitRecord{GameId: "game1", SensorId: "two", Hits: 1})
+
+  tests := []struct {
+    name    string
+    gameid  string
+    resume  bool
+    want    int
+  }{
+    {"resumed game keeps its hits", "game1", true, 2},
+    {"another game resumed starts over", "game2", true, 0},
+    {"the same game restarted starts over", "game2", false, 0},
+  }
+
+  for _, tt := range tests {
+    if err := j.Reset(tt.gameid, tt.resume); err != nil {
+      t.Fatalf("%s: cannot reset journal: %s", tt.name, err)
+    }
+    if got := len(j.List()); got != tt.want || j.GameId != tt.gameid {
+      t.Errorf("%s: %d hits of %s journaled, want %d of %s", tt.name, got, j.GameId, tt.want, tt.gameid)
+    }
+  }
+}
+
+func TestJournalPage(t *testing.T) {
+  j := NewJournal("", "node1")
+  for i := 0; i < 40; i++ {
+    j.Append(game.HitRecord{Id: uuid.New().String(), GameId: "game1", SensorId: "one", Color: "red", Team: "red", Hits: 1, Points: 1})
+  }
+
+  // paging through the journal the way the controller does gets every hit once, in order
+  limit := 1024 - constants.QUERY_RESPONSE_HEADROOM
+  seqs := []uint64{}
+  for pages := 1; len(seqs) < 40; pages++ {
+    data, err := j.Page("game1", len(seqs), limit)
+    if err != nil {
+      t.Fatalf("cannot page journal: %s", err)
+    }
+    if len(data) > limit {
+      t.Fatalf("page of %d bytes over the %d limit", len(data), limit)
+    }
+
+    var page game.HitRecordPage
+    if err := json.Unmarshal(data, &page); err != nil {
+      t.Fatalf("cannot parse page: %s", err)
+    }
+    if page.Total != 40 || len(page.Records) == 0 {
+      t.Fatalf("page %d has %d of %d records", pages, len(page.Records), page.Total)
+    }
+    for _, record := range page.Records {
+      seqs = append(seqs, record.Seq)
+    }
+    if pages > 40 {
+      t.Fatalf("still paging after %d pages", pages)
+    }
+  }
+  for i, seq := range seqs {
+    if seq != uint64(i + 1) {
+      t.Fatalf("paged hits %v, want 1 to 40 in order", seqs)
+    }
+  }
+
+  tests := []struct {
+    name    string
+    gameid  string
+    offset  int
+    limit   int
+    records int
+    total   int
+  }{
+    {"another game", "game2", 0, limit, 0, 0},
+    {"past the end", "game1", 40, limit, 0, 0},
+    {"the last one", "game1", 39, limit, 1, 40},
+    {"one record does not fit", "game1", 0, 10, 1, 40},   // sent anyway, the query response fails
+  }
+
+  for _, tt := range tests {
+    data, err := j.Page(tt.gameid, tt.offset, tt.limit)
+    if err != nil {
+      t.Fatalf("%s: cannot page journal: %s", tt.name, err)
+    }
+    var page game.HitRecordPage
+    if err := json.Unmarshal(data, &page); err != nil {
+      t.Fatalf("%s: cannot parse page: %s", tt.name, err)
+    }
+    if len(page.Records) != tt.records || page.Total != tt.total {
+      t.Errorf("%s: %d of %d records, want %d of %d", tt.name, len(page.Records), page.Total, tt.records, tt.total)
+    }
+  }
+}
@@ -5,12 +5,15 @@ import (
   "fmt"
   "time"
   "sync"
+  "slices"
   "strings"
   "context"
+  "math"
   "math/rand"
   "encoding/json"
 
   "golang.org/x/sync/errgroup"
+  "github.com/google/uuid"
   "github.com/hashicorp/serf/serf"
 
   "github.com/taemon1337/arena-nerf/pkg/common"
@@ -21,13 +24,28 @@ import (
   "github.com/taemon1337/arena-nerf/pkg/sensor"
 )
 
+// SensorRun is a running sensor's goroutine
+type SensorRun struct {
+  cancel        context.CancelFunc
+  done          chan struct{}
+}
+
 type Node struct {
   conf          *config.Config
   conn          *connector.Connector
   sensors       map[string]*sensor.Sensor
+  sensorruns    map[string]*SensorRun   // stops each running sensor, to restart it with a new config
+  sensorlock    *sync.Mutex
   gamechan      *game.GameChannel
   nodestate     *NodeState
+  journal       *Journal
   nodelock      *sync.Mutex
+  clock         common.Clock
+  rand          *rand.Rand    // reseeded from each game's seed (see GAME_ACTION_RESET)
+  startedAt     time.Time     // hit records are timed from here on the node's clock (monotonic when real)
+  testhits      chan string   // takes the sensor hits while a self test is running
+  testdone      chan struct{} // closed to stop a running self test when a game starts
+  Dropped       *common.Counter
   *log.Logger
 }
 
@@ -39,8 +57,17 @@ func NewNode(cfg *config.Config, gamechan *game.GameChannel, logger *log.Logger)
     conn:       connector.NewConnector(cfg, logger),
     gamechan:   gamechan,
     sensors:    map[string]*sensor.Sensor{},
+    sensorruns: map[string]*SensorRun{},
+    sensorlock: &sync.Mutex{},
     nodestate:  NewNodeState(cfg.AgentConf.NodeName),
+    journal:    NewJournal(cfg.JournalDir, cfg.AgentConf.NodeName),
     nodelock:   &sync.Mutex{},
+    clock:      common.NewRealClock(),
+    rand:       common.NewRand(common.NewSeed()),
+    startedAt:  time.Now(),
+    testhits:   nil,
+    testdone:   nil,
+    Dropped:    common.NewCounter(),
     Logger:     logger,
   }
 }
@@ -49,6 +76,14 @@ func (n *Node) Start(ctx context.Context) error {
   n.Printf("starting node")
   g, ctx := errgroup.WithContext(ctx)
 
+  // pick the hits of a game back up after a restart, they are pushed again once the controller is seen (see HandleEvent)
+  if err := n.journal.Load(); err != nil {
+    n.Printf("error loading hit journal: %s", err)
+  } else if entries := n.journal.List(); len(entries) > 0 {
+    n.Printf("restored %d journaled hits for game %s", len(entries), n.journal.GameId)
+    n.nodestate.Restore(n.journal.GameId, entries)
+  }
+
   if n.conf.EnableConnector {
     err := n.conn.Connect()
     if err != nil {
@@ -72,17 +107,16 @@ func (n *Node) Start(ctx context.Context) error {
         return err
       }
 
+      n.sensorlock.Lock()
       n.sensors[id] = sensor.NewSensor(id, sensconf, n.gamechan, n.Logger, n.conf.EnableLeds, n.conf.EnableHits)
+      n.sensorlock.Unlock()
     }
   } else {
     n.Printf("sensors disabled")
   }
 
-  for id, _ := range n.sensors {
-    sens := n.GetSensorById(id) // local variable needed to store id inside loop (otherwise it will call the same sensor 2x)
-    g.Go(func() error {
-      return sens.Start(ctx)
-    })
+  for _, id := range n.SensorIds() {
+    g.Go(n.RunSensor(ctx, id))
     n.Printf("started sensor %s", id)
   }
 
@@ -99,9 +133,63 @@ func (n *Node) Start(ctx context.Context) error {
             }
 
             n.Printf("node received sensor hit: %s", e)
-            n.nodestate.AddNodeHit(sensorid, sensorcolor, hitcount)
+            if n.TestHit(sensorid) {
+              continue
+            }
+
+            if n.nodestate.Status == constants.GAME_STATUS_STARTING {
+              n.Printf("false start on sensor %s - game has not started", sensorid)
+              pay := strings.Join([]string{n.conf.AgentConf.NodeName, sensorid, sensorcolor}, constants.SPLIT)
+              n.ReportToController(constants.FALSE_START, []byte(pay))
+              continue
+            }
+
+            if n.nodestate.Status == constants.GAME_STATUS_PAUSED {
+              n.Printf("game is paused - no hits allowed")
+              continue
+            }
+
+            if n.nodestate.OffHill() {
+              n.Printf("node is not the hill - ignoring hit")
+              continue
+            }
+
+            if n.nodestate.DamageMode() && n.nodestate.IsEliminated(sensorid) {
+              n.Printf("sensor %s has been eliminated - ignoring hit", sensorid)
+              continue
+            }
+
+            if n.nodestate.TargetMode() {
+              if sensorid != n.nodestate.Target {
+                n.Printf("sensor %s is not the target - ignoring hit", sensorid)
+                pay := strings.Join([]string{n.conf.AgentConf.NodeName, sensorid}, constants.SPLIT)
+                n.ReportToController(constants.TARGET_MISS, []byte(pay))
+                continue
+              }
+              n.HitTarget(sensorid)
+            }
+
+            if n.nodestate.CaptureMode() {
+              // a sensor cannot tell who fired, so a hit rotates it to the next team (team hits capture directly)
+              n.nodestate.AddSensorHit(sensorid, hitcount)
+              n.ReportHit(sensorid, sensorcolor, "", hitcount, 0)
+              n.CaptureSensor(sensorid, n.nodestate.NextTeam(sensorcolor))
+              continue
+            }
+
+            points := n.nodestate.AddNodeHit(sensorid, sensorcolor, hitcount)
+            n.ReportHit(sensorid, sensorcolor, sensorcolor, hitcount, points)
             n.Printf("node recorded sensor hit: %s", e)
+
+            if n.nodestate.DamageMode() {
+              pay := strings.Join([]string{n.conf.AgentConf.NodeName, sensorid, sensorcolor}, constants.SPLIT)
+              n.ReportToController(constants.TARGET_DAMAGE, []byte(pay))
+            }
             continue
+          case constants.SENSOR_CONFIG:
+            if err := n.UpdateSensor(ctx, string(e.Payload)); err != nil {
+              n.Printf("error updating sensor with %s: %s", string(e.Payload), err)
+            }
           default:
             n.Printf("node received game event: %s", e)
         }
@@ -121,6 +209,8 @@ func (n *Node) Start(ctx context.Context) error {
 }
 
 func (n *Node) GetSensorById(id string) *sensor.Sensor {
+  n.sensorlock.Lock()
+  defer n.sensorlock.Unlock()
   if _, ok := n.sensors[id]; !ok {
     return nil
   }
@@ -128,25 +218,148 @@ func (n *Node) GetSensorById(id string) *sensor.Sensor {
 }
 
 func (n *Node) Close() {
-  for id, _ := range n.sensors {
-    sens := n.GetSensorById(id)
-    sens.Close()
+  for _, id := range n.SensorIds() {
+    if sens := n.GetSensorById(id); sens != nil {
+      sens.Close()
+    }
+  }
+}
+
+// RunSensor returns a sensor's goroutine, which runs until the node stops or the sensor is stopped (see StopSensor)
+func (n *Node) RunSensor(ctx context.Context, id string) func() error {
+  sens := n.GetSensorById(id)
+  sensctx, cancel := context.WithCancel(ctx)
+  run := &SensorRun{cancel: cancel, done: make(chan struct{})}
+
+  n.sensorlock.Lock()
+  n.sensorruns[id] = run
+  n.sensorlock.Unlock()
+
+  return func() error {
+    defer close(run.done)
+    err := sens.Start(sensctx)
+    if ctx.Err() == nil && sensctx.Err() != nil {
+      return nil // stopped to change its config, not an error for the node
+    }
+    return err
   }
 }
 
+// StopSensor stops a running sensor and waits for it to let go of its gpio lines
+func (n *Node) StopSensor(id string) {
+  n.sensorlock.Lock()
+  run, ok := n.sensorruns[id]
+  delete(n.sensorruns, id)
+  n.sensorlock.Unlock()
+
+  if ok {
+    run.cancel()
+    <-run.done
+  }
+}
+
+// UpdateSensor applies a sensor change pushed from the controller, restarting only that sensor, and saves the
+// sensors so the change outlives the node
+func (n *Node) UpdateSensor(ctx context.Context, update string) error {
+  if !n.conf.EnableSensors {
+    return constants.ERR_SENSORS_DISABLED
+  }
+
+  id, err := n.conf.SensorsConf.Update(update)
+  if err != nil {
+    return err
+  }
+
+  n.Printf("stopping sensor %s to apply %s", id, update)
+  n.StopSensor(id)
+
+  n.sensorlock.Lock()
+  delete(n.sensors, id)
+  if cfg, ok := n.conf.SensorsConf.Configs[id]; ok {
+    n.sensors[id] = sensor.NewSensor(id, cfg, n.gamechan, n.Logger, n.conf.EnableLeds, n.conf.EnableHits)
+  }
+  n.sensorlock.Unlock()
+
+  if n.GetSensorById(id) != nil {
+    run := n.RunSensor(ctx, id)
+    go func() {
+      // a bad config pushed from the controller only takes down its own sensor
+      if err := run(); err != nil {
+        n.Printf("sensor %s stopped with error: %s", id, err)
+      }
+    }()
+    n.Printf("restarted sensor %s", id)
+  }
+
+  return n.conf.SaveSensors()
+}
+
 // handle event are events from serf (over the network)
 func (n *Node) HandleEvent(evt serf.Event) {
   if evt.EventType() == serf.EventUser {
     e := evt.(serf.UserEvent)
-    switch e.Name {
+    arena, name := common.ParseArenaEvent(e.Name)
+    if arena != "" && arena != n.Arena() {
+      return // an event for the nodes of another arena
+    }
+
+    switch name {
       case constants.GAME_MODE:
         n.Printf("set game mode to %s", string(e.Payload))
-        n.nodestate.Mode = string(e.Payload)
+        n.nodestate.SetMode(string(e.Payload))
+      case constants.NODE_SELFTEST, n.NodeEventName(constants.NODE_SELFTEST):
+        window, err := time.ParseDuration(string(e.Payload))
+        if err != nil {
+          n.Printf("error parsing self test window: %s", err)
+          return
+        }
+        go n.SelfTest(window)
+      case n.NodeEventName(constants.SENSOR_CONFIG):
+        // applied by the node loop, which owns the running sensors
+        select {
+          case n.gamechan.GameChan <- game.NewGameEvent(constants.SENSOR_CONFIG, e.Payload):
+          default:
+            n.Printf("game chan is full - discarding event: %s", e.Name)
+            n.Dropped.Add(constants.DROPPED_GAME_CHAN)
+        }
+      case n.NodeEventName(constants.NODE_IDENTIFY):
+        n.Printf("identifying node")
+        if err := n.SendEventToSensors(game.NewGameEvent(constants.SENSOR_FLASH, []byte(constants.IDENTIFY_FLASHES))); err != nil {
+          n.Printf("error flashing sensors: %s", err)
+        }
+      case constants.GAME_ACTION_COUNTDOWN:
+        startat, err := common.ParseStartAt(e.Payload)
+        if err != nil {
+          n.Printf("error parsing countdown: %s", err)
+          return
+        }
+        n.Printf("game starts at %s", startat.Format(time.RFC3339Nano))
+        n.StopSelfTest()
+        n.Countdown(startat)
       case constants.GAME_ACTION_BEGIN:
         n.Printf("start game received")
+        n.StopSelfTest()
+        n.nodestate.Status = constants.GAME_STATUS_RUNNING
+      case constants.GAME_ACTION_PAUSE:
+        n.Printf("pause game received - %s", string(e.Payload))
+        n.nodestate.Status = constants.GAME_STATUS_PAUSED
+        if err := n.SendEventToSensors(game.NewGameEvent(constants.SENSOR_PAUSED, e.Payload)); err != nil {
+          n.Printf("error pausing sensors: %s", err)
+        }
+      case constants.GAME_ACTION_RESUME:
+        n.Printf("resume game received - %s", string(e.Payload))
         n.nodestate.Status = constants.GAME_STATUS_RUNNING
+        if err := n.SendEventToSensors(game.NewGameEvent(constants.SENSOR_RESUMED, e.Payload)); err != nil {
+          n.Printf("error resuming sensors: %s", err)
+        }
       case constants.GAME_ACTION_END:
         n.Printf("end game received")
+        if n.nodestate.Status == constants.GAME_STATUS_PAUSED {
+          // stop the paused pattern, the game was ended while paused
+          if err := n.SendEventToSensors(game.NewGameEvent(constants.SENSOR_RESUMED, e.Payload)); err != nil {
+            n.Printf("error resuming sensors: %s", err)
+          }
+        }
         n.nodestate.Status = constants.GAME_STATUS_ENDED
       case constants.GAME_TEAMS:
         n.Printf("set game teams - %s", string(e.Payload))
@@ -183,31 +396,61 @@ func (n *Node) HandleEvent(evt serf.Event) {
           return
         }
 
-        sensorid := parts[0]
-        color := parts[1]
-
-        if sensorid == constants.RANDOM_SENSOR_ID {
-          sensorid = n.RandomSensorId()
+        if err := n.SetSensorColor(parts[0], parts[1]); err != nil {
+          n.Printf("error sending event %s to sensor: %s", e.Name, err)
+          return
+        }
+      case n.NodeEventName(constants.TARGET_REQUEST):
+        n.Printf("node received target request: %s", string(e.Payload))
+        if n.nodestate.Status != constants.GAME_STATUS_RUNNING {
+          n.Printf("game is not active - cannot light target")
+          return
         }
 
-        if color == constants.RANDOM_COLOR_ID {
-          sens := n.GetSensorById(sensorid)
-          if sens == nil {
-            n.Printf("no sensor found named %s on this node", sensorid)
-            return 
-          }
-
-          led := sens.Led()
-          if led != nil {
-            currentcolor := led.GetColor()
-            color = n.RandomColor(currentcolor)
-          }
+        parts := strings.Split(string(e.Payload), constants.SPLIT)
+        if len(parts) != 2 {
+          n.Printf("error parsing target request: %s (should be <sensor-name>:<color>)", string(e.Payload))
+          return
         }
 
-        if err := n.SendEventToSensor(sensorid, game.NewGameEvent(constants.SENSOR_COLOR, []byte(color))); err != nil {
+        if err := n.LightTarget(parts[0], parts[1]); err != nil {
+          n.Printf("error lighting target: %s", err)
+          return
+        }
+      case n.NodeEventName(constants.TARGET_CLEAR):
+        n.Printf("node received target clear: %s", string(e.Payload))
+        n.ClearTarget()
+      case n.NodeEventName(constants.TARGET_DOWN):
+        sensorid := string(e.Payload)
+        n.Printf("sensor %s has been eliminated", sensorid)
+        n.nodestate.Eliminate(sensorid)
+        if err := n.SendEventToSensor(sensorid, game.NewGameEvent(constants.SENSOR_ELIMINATED, []byte(sensorid))); err != nil {
           n.Printf("error sending event %s to sensor: %s", e.Name, err)
+        }
+      case n.NodeEventName(constants.GAME_RULES):
+        rules := game.NewNodeRules()
+        if err := json.Unmarshal(e.Payload, rules); err != nil {
+          n.Printf("error parsing game rules: %s", err)
           return
         }
+        n.Printf("set game rules - %s", string(e.Payload))
+        n.nodestate.SetRules(rules)
+      case constants.GAME_PHASE:
+        phase := string(e.Payload)
+        n.Printf("game phase is now %s", phase)
+        n.nodestate.SetPhase(phase)
+        if err := n.FlashSensors(phase); err != nil {
+          n.Printf("error flashing sensors: %s", err)
+        }
+      case constants.GAME_HILL:
+        n.Printf("the hill is now %s", string(e.Payload))
+        n.nodestate.SetHill(string(e.Payload))
+        if n.nodestate.OffHill() {
+          // only the hill is lit, so go dark until the hill comes back here
+          if err := n.SetSensorColor(constants.ALL_SENSOR_ID, constants.NONE_COLOR_ID); err != nil {
+            n.Printf("error turning off sensors: %s", err)
+          }
+        }
       case n.NodeEventName(constants.TEAM_HIT):
         n.Printf("NODE EVENT: %s", e.Name)
         if n.nodestate.Status != constants.GAME_STATUS_RUNNING {
@@ -217,7 +460,7 @@ func (n *Node) HandleEvent(evt serf.Event) {
 
         parts := strings.Split(string(e.Payload), constants.SPLIT)
         if len(parts) < 2 {
-          log.Printf("cannot parse team hit from %s - should be <team>:<count>", string(e.Payload))
+          log.Printf("cannot parse team hit from %s - should be <team>:<count>[:<sensor-id>]", string(e.Payload))
         } else {
           team, hits, err := common.ParseTeamHit(e.Payload)
           if err != nil {
@@ -225,12 +468,38 @@ func (n *Node) HandleEvent(evt serf.Event) {
             return
           }
 
-          n.nodestate.AddTeamHit(team, hits)
+          if n.nodestate.OffHill() {
+            n.Printf("node is not the hill - ignoring team hit")
+            return
+          }
+
+          if n.nodestate.CaptureMode() {
+            // the team hit names the sensor it landed on, or any sensor of the node
+            sensorid := n.RandomSensorId()
+            if len(parts) > 2 && parts[2] != constants.RANDOM_SENSOR_ID {
+              sensorid = parts[2]
+            }
+            n.nodestate.AddSensorHit(sensorid, hits)
+            n.ReportHit(sensorid, team, "", hits, 0)
+            n.CaptureSensor(sensorid, team)
+            return
+          }
+
+          points := n.nodestate.AddTeamHit(team, hits)
+          n.ReportHit(constants.NONE_SENSOR_ID, team, team, hits, points)
         }
       default:
         n.Printf("unrecognized event - %s", e.Name)
     }
   }
+  if evt.EventType() == serf.EventMemberJoin {
+    // the controller (re)joining means it came back from a restart or partition, or this node just joined
+    for _, m := range evt.(serf.MemberEvent).Members {
+      if m.Tags[constants.TAG_CTRL] == constants.TAG_TRUE {
+        n.ReplayJournal()
+      }
+    }
+  }
   if evt.EventType() == serf.EventQuery {
     var err error = nil
     q := evt.(*serf.Query)
@@ -239,8 +508,54 @@ func (n *Node) HandleEvent(evt serf.Event) {
         err = q.Respond([]byte(constants.NODE_IS_READY))
       case constants.GAME_MODE:
         err = q.Respond([]byte(n.nodestate.Mode))
+      case constants.NODE_SENSORS:
+        err = q.Respond([]byte(strings.Join(n.SensorIds(), constants.COMMA)))
+      case constants.GAME_ACTION_RESET:
+        gameid, seed, resume, perr := common.ParseGameReset(q.Payload)
+        if perr != nil {
+          n.Printf("error parsing game reset: %s", perr)
+          return
+        }
+
+        if n.nodestate.Reset(gameid, uint64(q.LTime), resume) {
+          n.Printf("reset hits for game %s (seed %d, resume %t)", gameid, seed, resume)
+          n.Reseed(seed)
+          if jerr := n.journal.Reset(gameid, resume); jerr != nil {
+            n.Printf("error resetting hit journal: %s", jerr)
+          }
+        } else {
+          n.Printf("ignoring late reset for game %s", gameid)
+        }
+        err = q.Respond([]byte(n.nodestate.GameId))
+      case constants.NODE_HEALTH:
+        data, merr := json.Marshal(n.Health())
+        if merr != nil {
+          n.Printf("cannot marshal node health: %s", merr)
+          return
+        }
+        err = q.Respond(data)
+      case constants.NODE_HITS:
+        gameid, offset, perr := common.ParseHitRecordsQuery(q.Payload)
+        if perr != nil {
+          n.Printf("error parsing hit records query: %s", perr)
+          return
+        }
+
+        data, merr := n.journal.Page(gameid, offset, n.conf.SerfConf.QueryResponseSizeLimit - constants.QUERY_RESPONSE_HEADROOM)
+        if merr != nil {
+          n.Printf("cannot marshal hit records: %s", merr)
+          return
+        }
+        err = q.Respond(data)
       case constants.NODE_SCOREBOARD:
-        data, err := json.Marshal(n.nodestate.Hits)
+        hits, ok := n.nodestate.HitsFor(string(q.Payload))
+        if !ok {
+          // never mix the hits of two games, the engine treats a missing response as no hits
+          n.Printf("not counting hits for game %s - ignoring scoreboard query", string(q.Payload))
+          return
+        }
+
+        data, err := json.Marshal(hits)
         if err != nil {
           log.Printf("cannot marshal node hits: %s", err)
         } else {
@@ -265,7 +580,7 @@ func (n *Node) SendEventToSensor(sensorid string, e game.GameEvent) error {
     return constants.ERR_SENSORS_DISABLED
   }
 
-  if len(n.sensors) < 1 {
+  if len(n.SensorIds()) < 1 {
     return constants.ERR_NO_SENSORS
   }
 
@@ -273,31 +588,420 @@ func (n *Node) SendEventToSensor(sensorid string, e game.GameEvent) error {
     sensorid = n.RandomSensorId()
   }
 
-  if _, ok := n.sensors[sensorid]; !ok {
+  sens := n.GetSensorById(sensorid)
+  if sens == nil {
     return constants.ERR_NO_SENSOR_BY_NAME
   }
 
   select {
-    case n.sensors[sensorid].SensorChan <- e:
+    case sens.SensorChan <- e:
       n.Printf("sent event to sensor: %s", e)
     default:
       n.Printf("sensor chan is full - discarding event: %s", e)
+      sens.Dropped.Add(constants.DROPPED_SENSOR_CHAN)
   }
   return nil
 }
 
-func (n *Node) RandomSensorId() string {
-  i := rand.Intn(len(n.sensors))
-  p := 0
+// Countdown flashes the sensors each second up to the agreed start time and starts the game right on it
+func (n *Node) Countdown(startat time.Time) {
+  n.nodestate.Status = constants.GAME_STATUS_STARTING
 
-  for id, _ := range n.sensors {
-    if p == i {
-      return id
+  go func() {
+    for secs := int(math.Ceil(startat.Sub(n.clock.Now()).Seconds())); secs > 0; secs-- {
+      <-n.clock.After(startat.Add(time.Duration(-secs) * time.Second).Sub(n.clock.Now()))
+      if err := n.SendEventToSensors(game.NewGameEvent(constants.SENSOR_COUNTDOWN, []byte(fmt.Sprintf("%d", secs)))); err != nil {
+        n.Printf("error counting down sensors: %s", err)
+      }
+    }
+
+    <-n.clock.After(startat.Sub(n.clock.Now()))
+    if n.nodestate.Status == constants.GAME_STATUS_STARTING {
+      n.nodestate.Status = constants.GAME_STATUS_RUNNING
+      n.Printf("game started")
+      if err := n.SendEventToSensors(game.NewGameEvent(constants.SENSOR_COUNTDOWN, []byte("0"))); err != nil {
+        n.Printf("error counting down sensors: %s", err)
+      }
+    }
+  }()
+}
+
+// FlashSensors blinks every sensor so players can see the game has gone to a new phase
+func (n *Node) FlashSensors(phase string) error {
+  times := "3"
+  if phase == constants.GAME_PHASE_DRAW {
+    times = "5"
+  }
+
+  return n.SendEventToSensors(game.NewGameEvent(constants.SENSOR_FLASH, []byte(times)))
+}
+
+func (n *Node) SendEventToSensors(e game.GameEvent) error {
+  for _, id := range n.SensorIds() {
+    if err := n.SendEventToSensor(id, e); err != nil {
+      return err
+    }
+  }
+  return nil
+}
+
+// SetSensorColor sets the color of a sensor, where the sensor id may be "rand" or "all"
+// and the color may be "rand" (a random team color) or "none" (turned off)
+func (n *Node) SetSensorColor(sensorid, color string) error {
+  if sensorid == constants.ALL_SENSOR_ID {
+    for _, id := range n.SensorIds() {
+      if err := n.SetSensorColor(id, color); err != nil {
+        return err
+      }
+    }
+    return nil
+  }
+
+  if sensorid == constants.RANDOM_SENSOR_ID {
+    sensorid = n.RandomSensorId()
+  }
+
+  if color == constants.NONE_COLOR_ID {
+    color = ""
+  }
+
+  if color != "" && n.nodestate.IsEliminated(sensorid) {
+    return nil // eliminated sensors stay dark
+  }
+
+  if color == constants.RANDOM_COLOR_ID {
+    sens := n.GetSensorById(sensorid)
+    if sens == nil {
+      n.Printf("no sensor found named %s on this node", sensorid)
+      return constants.ERR_NO_SENSOR_BY_NAME
+    }
+
+    led := sens.Led()
+    if led != nil {
+      currentcolor := led.GetColor()
+      color = n.RandomColor(currentcolor)
+    }
+  }
+
+  return n.SendEventToSensor(sensorid, game.NewGameEvent(constants.SENSOR_COLOR, []byte(color)))
+}
+
+// LightTarget replaces the current target with the given sensor and color (either may be "rand")
+func (n *Node) LightTarget(sensorid, color string) error {
+  n.ClearTarget()
+
+  if sensorid == constants.RANDOM_SENSOR_ID {
+    sensorid = n.RandomSensorId()
+  }
+
+  if color == constants.RANDOM_COLOR_ID {
+    color = n.RandomColor("")
+  }
+
+  if err := n.SetSensorColor(sensorid, color); err != nil {
+    return err
+  }
+
+  n.nodestate.SetTarget(sensorid, color, n.clock.Now())
+  return nil
+}
+
+// ClearTarget turns off the current target sensor, if any
+func (n *Node) ClearTarget() {
+  if target := n.nodestate.ClearTarget(); target != "" {
+    if err := n.SetSensorColor(target, constants.NONE_COLOR_ID); err != nil {
+      n.Printf("error turning off target %s: %s", target, err)
     }
-    p += 1
   }
+}
+
+// HitTarget reports the reaction time of the target to the controller and turns it off
+func (n *Node) HitTarget(sensorid string) {
+  reaction := n.clock.Since(n.nodestate.TargetLitAt).Milliseconds()
+  pay := strings.Join([]string{n.conf.AgentConf.NodeName, sensorid, n.nodestate.TargetColor, fmt.Sprintf("%d", reaction)}, constants.SPLIT)
+  n.ClearTarget()
+  n.ReportToController(constants.TARGET_HIT, []byte(pay))
+}
+
+// ReportHit journals a record of the hit and pushes it to the controller so scores are live between scoreboard polls
+func (n *Node) ReportHit(sensorid, color, team string, hits, points int) {
+  now := n.clock.Now()
+  entry, err := n.journal.Append(game.HitRecord{
+    Id:       uuid.New().String(),
+    Node:     n.conf.AgentConf.NodeName,
+    GameId:   n.nodestate.GameId,
+    SensorId: sensorid,
+    Color:    color,
+    Team:     team,
+    Hits:     hits,
+    Points:   points,
+    Mono:     n.clock.Since(n.startedAt),
+    At:       now,
+    LTime:    n.conn.LamportTime(),
+  })
+  if err != nil {
+    n.Printf("error journaling hit on %s: %s", sensorid, err)
+  }
+
+  n.ReportHitToController(entry)
+}
+
+// ReportHitToController sends a hit report until the controller acks it, a report still not acked after
+// a few tries is left for the journal replay the next time the controller is seen
+func (n *Node) ReportHitToController(entry game.HitRecord) {
+  go func() {
+    for try := 1; ; try++ {
+      err := n.SendQueryToController(constants.HIT_REPORT, n.HitReport(entry))
+      if err == nil || err == constants.ERR_CONNECTOR_DISABLED {
+        return
+      }
+
+      if try >= constants.REPORT_RETRIES {
+        n.Printf("error reporting hit %d to controller: %s", entry.Seq, err)
+        return
+      }
+      <-n.clock.After(time.Duration(try) * time.Second)
+    }
+  }()
+}
+
+// HitReport is the payload of a hit report, the controller counts each run and sequence number once
+func (n *Node) HitReport(entry game.HitRecord) []byte {
+  pay := strings.Join([]string{n.conf.AgentConf.NodeName, entry.GameId, entry.Run, fmt.Sprintf("%d", entry.Seq), entry.SensorId, entry.Team, fmt.Sprintf("%d", entry.Hits), fmt.Sprintf("%d", entry.Points)}, constants.SPLIT)
+  return []byte(pay)
+}
+
+// ReplayJournal pushes every journaled hit of the current game to the controller again, in order,
+// so hits lost to a restart or partition get counted and the ones already counted are skipped
+func (n *Node) ReplayJournal() {
+  entries := n.journal.List()
+  if len(entries) == 0 {
+    return
+  }
+
+  go func() {
+    n.Printf("replaying %d journaled hits for game %s", len(entries), entries[0].GameId)
+    for _, entry := range entries {
+      if err := n.SendQueryToController(constants.HIT_REPORT, n.HitReport(entry)); err != nil {
+        n.Printf("error replaying hit %d: %s", entry.Seq, err)
+        return // the next time the controller is seen will try again
+      }
+    }
+  }()
+}
+
+// CaptureSensor switches a sensor over to the given team and reports the new owner to the controller
+func (n *Node) CaptureSensor(sensorid, team string) {
+  if !slices.Contains(n.nodestate.Teams, team) {
+    n.Printf("%s is not playing - cannot capture sensor %s", team, sensorid)
+    return
+  }
+
+  if n.GetSensorById(sensorid) == nil {
+    n.Printf("no sensor %s - cannot capture it", sensorid)
+    return
+  }
+
+  if err := n.SetSensorColor(sensorid, team); err != nil {
+    n.Printf("error setting captured sensor %s color: %s", sensorid, err)
+    return
+  }
+
+  n.Printf("sensor %s captured by %s", sensorid, team)
+  pay := strings.Join([]string{n.conf.AgentConf.NodeName, sensorid, team}, constants.SPLIT)
+  n.ReportToController(constants.TARGET_CAPTURE, []byte(pay))
+}
+
+// Arena is the arena this node plays in, set with -tag arena=<name>
+func (n *Node) Arena() string {
+  return n.conf.AgentConf.Tags[constants.TAG_ARENA]
+}
+
+// SendQueryToController sends a query to the controller and waits for it to acknowledge
+func (n *Node) SendQueryToController(name string, payload []byte) error {
+  if !n.conf.EnableConnector {
+    return constants.ERR_CONNECTOR_DISABLED
+  }
+
+  resp, err := n.conn.Query(common.ArenaEvent(n.Arena(), name), payload, &serf.QueryParam{FilterTags: constants.CTRL_TAGS})
+  if err != nil {
+    return err
+  }
+  defer resp.Close()
+
+  for r := range resp.ResponseCh() {
+    if string(r.Payload) == constants.QUERY_ACK {
+      return nil
+    }
+  }
+
+  return constants.ERR_NO_CONTROLLER_ACK
+}
+
+// ReportToController sends a query to the controller without blocking the caller
+func (n *Node) ReportToController(name string, payload []byte) {
+  go func() {
+    if err := n.SendQueryToController(name, payload); err != nil {
+      n.Printf("error reporting %s to controller: %s", name, err)
+    }
+  }()
+}
+
+// SelfTest blinks each sensor in turn and waits for a test hit on it, then reports which sensors passed,
+// it is refused during a game as it takes over the sensor hits, and stopped when a game starts (see StopSelfTest)
+func (n *Node) SelfTest(window time.Duration) {
+  n.nodelock.Lock()
+  if n.nodestate.InGame() {
+    n.nodelock.Unlock()
+    n.Printf("cannot self test during a game")
+    return
+  }
+  if n.testhits != nil {
+    n.nodelock.Unlock()
+    n.Printf("self test already running")
+    return
+  }
+  hits := make(chan string, constants.CHANNEL_WIDTH)
+  done := make(chan struct{})
+  n.testhits = hits
+  n.testdone = done
+  n.nodelock.Unlock()
+
+  defer func() {
+    n.nodelock.Lock()
+    n.testhits = nil
+    if n.testdone == done {
+      n.testdone = nil
+    }
+    n.nodelock.Unlock()
+  }()
+
+  results := []string{}
+  for _, id := range n.SensorIds() {
+    n.Printf("self testing sensor %s - hit it within %s", id, window)
+    if err := n.SendEventToSensor(id, game.NewGameEvent(constants.SENSOR_FLASH, []byte("3"))); err != nil {
+      n.Printf("error flashing sensor %s: %s", id, err)
+    }
+
+    result := constants.SELFTEST_FAIL
+    if WaitForTestHit(id, hits, done, n.clock.After(window)) {
+      result = constants.SELFTEST_PASS
+    }
+
+    select {
+      case <-done:
+        n.Printf("self test stopped - a game is starting")
+        return
+      default:
+    }
+    n.Printf("sensor %s self test: %s", id, result)
+    results = append(results, id + "=" + result)
+  }
+
+  pay := strings.Join([]string{n.conf.AgentConf.NodeName, strings.Join(results, constants.COMMA)}, constants.SPLIT)
+  n.ReportToController(constants.SELFTEST_RESULT, []byte(pay))
+}
+
+// WaitForTestHit is true if the sensor is hit before the timeout fires (or the test is stopped),
+// hits on other sensors don't count
+func WaitForTestHit(sensorid string, hits chan string, done chan struct{}, timeout <-chan time.Time) bool {
+  for {
+    select {
+      case hit := <-hits:
+        if hit == sensorid {
+          return true
+        }
+      case <-done:
+        return false
+      case <-timeout:
+        return false
+    }
+  }
+}
+
+// StopSelfTest stops a running self test, so a game never loses its hits to one
+func (n *Node) StopSelfTest() {
+  n.nodelock.Lock()
+  defer n.nodelock.Unlock()
+  if n.testdone != nil {
+    close(n.testdone)
+    n.testdone = nil
+  }
+}
+
+// TestHit hands a sensor hit to a running self test, returning false when there is none or a game is on
+func (n *Node) TestHit(sensorid string) bool {
+  n.nodelock.Lock()
+  defer n.nodelock.Unlock()
+  if n.testhits == nil || n.testdone == nil || n.nodestate.InGame() {
+    return false
+  }
+
+  select {
+    case n.testhits <- sensorid:
+    default:
+      n.Printf("self test chan is full - discarding hit on %s", sensorid)
+      n.Dropped.Add(constants.DROPPED_SELFTEST_CHAN)
+  }
+  return true
+}
+
+// Health is how the node and its sensors are doing, for the controller to spot a dead sensor before a game
+func (n *Node) Health() game.NodeHealth {
+  sensors := []game.SensorHealth{}
+  for _, id := range n.SensorIds() {
+    if sens := n.GetSensorById(id); sens != nil {
+      sensors = append(sensors, sens.Health())
+    }
+  }
+
+  return game.NodeHealth{
+    Node:     n.conf.AgentConf.NodeName,
+    Version:  constants.VERSION,
+    Uptime:   n.clock.Since(n.startedAt),
+    Status:   n.nodestate.Status,
+    Mode:     n.nodestate.Mode,
+    GameId:   n.nodestate.GameId,
+    Sensors:  sensors,
+    Dropped:  n.Dropped.Counts(),
+  }
+}
+
+// SensorIds lists the ids of all sensors on this node in sorted order
+func (n *Node) SensorIds() []string {
+  n.sensorlock.Lock()
+  defer n.sensorlock.Unlock()
+  ids := []string{}
+  for id, _ := range n.sensors {
+    ids = append(ids, id)
+  }
+  slices.Sort(ids)
+  return ids
+}
+
+// RandomSensorId picks from the sorted sensor ids, so the same seed picks the same sensors
+func (n *Node) RandomSensorId() string {
+  ids := n.SensorIds()
+  if len(ids) == 0 {
+    return ""
+  }
+
+  n.nodelock.Lock()
+  defer n.nodelock.Unlock()
+  return ids[n.rand.Intn(len(ids))]
+}
+
+// Reseed restarts the node's randomness from the game's seed
+func (n *Node) Reseed(seed int64) {
+  n.nodelock.Lock()
+  defer n.nodelock.Unlock()
+  n.rand = common.NewRand(seed)
+}
 
-  return ""
+// SetClock swaps the real clock for another (i.e. a manual one)
+func (n *Node) SetClock(clock common.Clock) {
+  n.clock = clock
+  n.startedAt = clock.Now()
 }
 
 func (n *Node) RandomColor(except_color string) string {
@@ -316,5 +1020,7 @@ func (n *Node) RandomColor(except_color string) string {
     }
   }
 
-  return availableColors[rand.Intn(len(availableColors))]
+  n.nodelock.Lock()
+  defer n.nodelock.Unlock()
+  return availableColors[n.rand.Intn(len(availableColors))]
 }
@@ -2,14 +2,26 @@ package node
 
 import (
   "sync"
+  "time"
+  "slices"
   "strings"
   "github.com/taemon1337/arena-nerf/pkg/constants"
+  "github.com/taemon1337/arena-nerf/pkg/game"
 )
 
 type NodeState struct {
   Name          string          `yaml:"name" json:"name"`
+  GameId        string          `yaml:"game_id" json:"game_id"`           // the game the hits are counted for
+  ResetLTime    uint64          `yaml:"reset_ltime" json:"reset_ltime"`   // lamport time of the last reset, older resets are ignored
   Status        string          `yaml:"status" json:"status"`
   Mode          string          `yaml:"mode" json:"mode"`
+  Phase         string          `yaml:"phase" json:"phase"`
+  Hill          string          `yaml:"hill" json:"hill"`
+  Target        string          `yaml:"target" json:"target"`
+  TargetColor   string          `yaml:"target_color" json:"target_color"`
+  TargetLitAt   time.Time       `yaml:"target_lit_at" json:"target_lit_at"`
+  Eliminated    []string        `yaml:"eliminated" json:"eliminated"`
+  Rules         *game.NodeRules `yaml:"rules" json:"rules"`
   Teams         []string        `yaml:"teams" json:"teams"`
   Colors        []string        `yaml:"colors" json:"colors"`
   Hits          map[string]int  `yaml:"hits" json:"hits"`
@@ -19,8 +31,17 @@ type NodeState struct {
 func NewNodeState(name string) *NodeState {
   return &NodeState{
     Name:         name,
+    GameId:       "",
+    ResetLTime:   0,
     Status:       constants.GAME_STATUS_INIT,
     Mode:         "",
+    Phase:        constants.GAME_PHASE_REGULATION,
+    Hill:         "",
+    Target:       "",
+    TargetColor:  "",
+    TargetLitAt:  time.Time{},
+    Eliminated:   []string{},
+    Rules:        game.NewNodeRules(),
     Teams:        []string{},
     Colors:       []string{},
     Hits:         map[string]int{name: 0},
@@ -28,6 +49,11 @@ func NewNodeState(name string) *NodeState {
   }
 }
 
+// InGame is true from the countdown on, while the sensor hits count for the game
+func (ns *NodeState) InGame() bool {
+  return ns.Status == constants.GAME_STATUS_RUNNING || ns.Status == constants.GAME_STATUS_STARTING
+}
+
 func (ns *NodeState) SetTeams(teams string, enable_team_colors bool) {
   ns.nodelock.Lock()
   defer ns.nodelock.Unlock()
@@ -37,11 +63,141 @@ func (ns *NodeState) SetTeams(teams string, enable_team_colors bool) {
   }
 }
 
-func (ns *NodeState) AddTeamHit(team string, count int) {
-  ns.AddNodeHit(constants.NONE_SENSOR_ID, team, count)
+// SetMode sets the game mode, clearing anything left over from the last game's mode
+func (ns *NodeState) SetMode(mode string) {
+  ns.nodelock.Lock()
+  defer ns.nodelock.Unlock()
+  ns.Mode = mode
+  ns.Phase = constants.GAME_PHASE_REGULATION
+  ns.Hill = ""
+  ns.Target = ""
+  ns.TargetColor = ""
+  ns.Eliminated = []string{}
+  ns.Rules = game.NewNodeRules()
+}
+
+// Reset zeroes the hits for a new game, unless a newer reset (by lamport time) has already been seen,
+// a resumed game keeps the hits if they were already being counted for it
+func (ns *NodeState) Reset(gameid string, ltime uint64, resume bool) bool {
+  ns.nodelock.Lock()
+  defer ns.nodelock.Unlock()
+  if ltime < ns.ResetLTime {
+    return false
+  }
+  ns.ResetLTime = ltime
+  ns.Status = constants.GAME_STATUS_INIT
+  if resume && ns.GameId == gameid {
+    return true
+  }
+  ns.GameId = gameid
+  ns.Hits = map[string]int{ns.Name: 0}
+  return true
+}
+
+// HitsFor returns a copy of the hits, but only if they are being counted for the game
+func (ns *NodeState) HitsFor(gameid string) (map[string]int, bool) {
+  ns.nodelock.Lock()
+  defer ns.nodelock.Unlock()
+  if gameid != ns.GameId {
+    return nil, false
+  }
+
+  hits := map[string]int{}
+  for key, count := range ns.Hits {
+    hits[key] = count
+  }
+  return hits, true
+}
+
+func (ns *NodeState) SetPhase(phase string) {
+  ns.nodelock.Lock()
+  defer ns.nodelock.Unlock()
+  ns.Phase = phase
+}
+
+func (ns *NodeState) SetRules(rules *game.NodeRules) {
+  ns.nodelock.Lock()
+  defer ns.nodelock.Unlock()
+  ns.Rules = rules
+}
+
+func (ns *NodeState) SetHill(hill string) {
+  ns.nodelock.Lock()
+  defer ns.nodelock.Unlock()
+  ns.Hill = hill
+}
+
+// OffHill is true in king of the hill games when another node is the hill
+func (ns *NodeState) OffHill() bool {
+  hillmode := ns.Mode == constants.GAME_MODE_KING_OF_THE_HILL || ns.Rules.Lighting == constants.LIGHTING_HILL
+  return hillmode && ns.Hill != "" && ns.Hill != ns.Name
+}
+
+func (ns *NodeState) SetTarget(sensorid, color string, litat time.Time) {
+  ns.nodelock.Lock()
+  defer ns.nodelock.Unlock()
+  ns.Target = sensorid
+  ns.TargetColor = color
+  ns.TargetLitAt = litat
+}
+
+// ClearTarget turns off the target, returning the sensor it was on
+func (ns *NodeState) ClearTarget() string {
+  ns.nodelock.Lock()
+  defer ns.nodelock.Unlock()
+  target := ns.Target
+  ns.Target = ""
+  ns.TargetColor = ""
+  return target
+}
+
+// TargetMode is true in games where only the single lit target counts
+func (ns *NodeState) TargetMode() bool {
+  switch ns.Mode {
+    case constants.GAME_MODE_WHACK_A_MOLE, constants.GAME_MODE_DRILL, constants.GAME_MODE_TIME_TRIAL:
+      return true
+  }
+  return ns.Rules.Lighting == constants.LIGHTING_TARGET
+}
+
+// DamageMode is true in games where sensors have health and can be eliminated
+func (ns *NodeState) DamageMode() bool {
+  return ns.Mode == constants.GAME_MODE_ELIMINATION
+}
+
+func (ns *NodeState) Eliminate(sensorid string) {
+  ns.nodelock.Lock()
+  defer ns.nodelock.Unlock()
+  if !slices.Contains(ns.Eliminated, sensorid) {
+    ns.Eliminated = append(ns.Eliminated, sensorid)
+  }
+}
+
+func (ns *NodeState) IsEliminated(sensorid string) bool {
+  return slices.Contains(ns.Eliminated, sensorid)
 }
 
-func (ns *NodeState) AddNodeHit(sensorid, sensorcolor string, hitcount int) {
+// CaptureMode is true in games where a hit switches the sensor over to another team
+func (ns *NodeState) CaptureMode() bool {
+  return ns.Mode == constants.GAME_MODE_TERRITORY
+}
+
+// NextTeam returns the team after the given one, wrapping around (or the first team if none)
+func (ns *NodeState) NextTeam(team string) string {
+  if len(ns.Teams) == 0 {
+    return ""
+  }
+
+  i := slices.Index(ns.Teams, team)
+  return ns.Teams[(i + 1) % len(ns.Teams)]
+}
+
+func (ns *NodeState) AddTeamHit(team string, count int) int {
+  return ns.AddNodeHit(constants.NONE_SENSOR_ID, team, count)
+}
+
+// AddNodeHit counts a hit on the node and sensor and credits the points to the team/color, returning the points
+func (ns *NodeState) AddNodeHit(sensorid, sensorcolor string, hitcount int) int {
   ns.nodelock.Lock()
   defer ns.nodelock.Unlock()
 
@@ -56,5 +212,32 @@ func (ns *NodeState) AddNodeHit(sensorid, sensorcolor string, hitcount int) {
 
   ns.Hits[ns.Name] += hitcount // total node hits
   ns.Hits[sensorid] += hitcount // total sensor hits
-  ns.Hits[sensorcolor] += hitcount // total team/color hits
+  points := hitcount * ns.Rules.Points(sensorid)
+  ns.Hits[sensorcolor] += points // total team/color points
+  ns.Hits[constants.HITS_PREFIX + sensorcolor] += hitcount // total team/color hits
+  return points
+}
+
+// Restore counts the journaled hits of a game again, after the node restarted in the middle of it
+func (ns *NodeState) Restore(gameid string, entries []game.HitRecord) {
+  ns.nodelock.Lock()
+  defer ns.nodelock.Unlock()
+  ns.GameId = gameid
+  ns.Hits = map[string]int{ns.Name: 0}
+  for _, e := range entries {
+    ns.Hits[ns.Name] += e.Hits
+    ns.Hits[e.SensorId] += e.Hits
+    if e.Team != "" {
+      ns.Hits[e.Team] += e.Points
+      ns.Hits[constants.HITS_PREFIX + e.Team] += e.Hits
+    }
+  }
+}
+
+// AddSensorHit counts a hit on the node and sensor without crediting any team
+func (ns *NodeState) AddSensorHit(sensorid string, hitcount int) {
+  ns.nodelock.Lock()
+  defer ns.nodelock.Unlock()
+  ns.Hits[ns.Name] += hitcount
+  ns.Hits[sensorid] += hitcount
 }
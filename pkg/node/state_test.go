@@ -0,0 +1,38 @@
+package node
+
+import (
+  "testing"
+)
+
+func TestNextTeam(t *testing.T) {
+  ns := NewNodeState("node1")
+  ns.SetTeams("red,blue,green", false)
+
+  tests := []struct {
+    owner   string
+    want    string
+  }{
+    {"red", "blue"},
+    {"blue", "green"},
+    {"green", "red"},   // wraps around
+    {"", "red"},        // an unowned sensor goes to the first team
+    {"yellow", "red"},  // so does a color no team plays
+  }
+
+  for _, tt := range tests {
+    got := ns.NextTeam(tt.owner)
+    if got != tt.want {
+      t.Errorf("hit on a %q sensor captured for %q, want %q", tt.owner, got, tt.want)
+    }
+    if got == tt.owner {
+      t.Errorf("hit on a %q sensor did not change its owner", tt.owner)
+    }
+  }
+}
+
+func TestNextTeamNoTeams(t *testing.T) {
+  ns := NewNodeState("node1")
+  if got := ns.NextTeam("red"); got != "" {
+    t.Fatalf("captured for %q with no teams set", got)
+  }
+}
@@ -7,6 +7,7 @@ import (
   "context"
   "golang.org/x/sync/errgroup"
   "github.com/taemon1337/gpiod"
+  "github.com/taemon1337/arena-nerf/pkg/common"
   "github.com/taemon1337/arena-nerf/pkg/constants"
   "github.com/taemon1337/arena-nerf/pkg/config"
   "github.com/taemon1337/arena-nerf/pkg/game"
@@ -20,10 +21,11 @@ type SensorHitInput struct {
   HitChan       chan game.GameEvent     `yaml:"-" json:"-"`
   lasthit       time.Time               `yaml:"last_hit" json:"last_hit"`
   lock          *sync.Mutex             `yaml:"-" json:"-"`
+  dropped       *common.Counter         `yaml:"-" json:"-"`
   *log.Logger
 }
 
-func NewSensorHitInput(cfg *config.SensorConfig, logger *log.Logger) *SensorHitInput {
+func NewSensorHitInput(cfg *config.SensorConfig, dropped *common.Counter, logger *log.Logger) *SensorHitInput {
   return &SensorHitInput{
     conf:           cfg,
     color:          "",
@@ -32,6 +34,7 @@ func NewSensorHitInput(cfg *config.SensorConfig, logger *log.Logger) *SensorHitI
     HitChan:        make(chan game.GameEvent, constants.CHANNEL_WIDTH),
     lasthit:        time.Now(),
     lock:           &sync.Mutex{},
+    dropped:        dropped,
     Logger:   logger,
   }
 }
@@ -55,6 +58,7 @@ func (s *SensorHitInput) ProcessEvent(evt gpiod.LineEvent) {
       s.Printf("successfully sent %s event to hit chan", constants.SENSOR_HIT)
     default:
       s.Printf("hit channel is full - discarding event: %s", evt)
+      s.dropped.Add(constants.DROPPED_HIT_CHAN)
   }
 }
 
@@ -72,6 +76,7 @@ func (s *SensorHitInput) Start(parentctx context.Context) error {
     case s.hitchan <- evt:
     default:
       s.Printf("event chan overflow - discarding event")
+      s.dropped.Add(constants.DROPPED_GPIO_CHAN)
     }
   }
 
@@ -103,6 +108,11 @@ func (s *SensorHitInput) Start(parentctx context.Context) error {
   return g.Wait()
 }
 
+// Held is true once the gpio hit line has been requested
+func (s *SensorHitInput) Held() bool {
+  return s.line != nil
+}
+
 func (s *SensorHitInput) Close() {
   s.line.Reconfigure(gpiod.AsInput)
   s.line.Close()
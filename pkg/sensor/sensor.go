@@ -3,9 +3,13 @@ package sensor
 import (
   "fmt"
   "log"
+  "time"
+  "strconv"
   "strings"
   "context"
+  "sync"
   "golang.org/x/sync/errgroup"
+  "github.com/taemon1337/arena-nerf/pkg/common"
   "github.com/taemon1337/arena-nerf/pkg/config"
   "github.com/taemon1337/arena-nerf/pkg/constants"
   "github.com/taemon1337/arena-nerf/pkg/game"
@@ -23,11 +27,15 @@ type Sensor struct {
   hit           *SensorHitInput
   enableLeds    bool
   enableHits    bool
+  lasthit       time.Time
+  lock          *sync.Mutex
+  Dropped       *common.Counter   // events lost to a full channel
   *log.Logger
 }
 
 func NewSensor(id string, cfg *config.SensorConfig, gamechan *game.GameChannel, logger *log.Logger, enable_leds, enable_hits bool) *Sensor {
   logger = log.New(logger.Writer(), fmt.Sprintf("[sensor:%s]: ", id), logger.Flags())
+  dropped := common.NewCounter()
 
   return &Sensor{
     id:           id,
@@ -36,9 +44,12 @@ func NewSensor(id string, cfg *config.SensorConfig, gamechan *game.GameChannel,
     SensorChan:   make(chan game.GameEvent, constants.CHANNEL_WIDTH),
     led:          NewSensorLed(cfg, logger),
     ledstrip:     NewLedStrip(cfg, logger),
-    hit:          NewSensorHitInput(cfg, logger),
+    hit:          NewSensorHitInput(cfg, dropped, logger),
     enableLeds:   enable_leds,
     enableHits:   enable_hits,
+    lasthit:      time.Time{},
+    lock:         &sync.Mutex{},
+    Dropped:      dropped,
     Logger:       logger,
   }
 }
@@ -70,8 +81,13 @@ func (s *Sensor) Start(parentctx context.Context) error {
   }
 
   g.Go(func() error {
+    var paused *time.Ticker
+    var pausedblink <-chan time.Time // blinks the paused pattern while set
+
     for {
       select {
+      case <-pausedblink:
+        s.Flash(1, RGB{255, 255, 0})
       case evt := <-s.hit.HitChan:
         s.Printf("HIT CHAN: %s", evt)
         s.SensorHit(s.id)
@@ -85,6 +101,37 @@ func (s *Sensor) Start(parentctx context.Context) error {
           case constants.SENSOR_COLOR:
             s.Printf("sensor received sensor color game event: %s", evt)
             s.led.SetColor(string(evt.Payload))
+          case constants.SENSOR_ELIMINATED:
+            s.Printf("sensor has been eliminated")
+            s.Flash(3, RGB{255, 0, 0})
+            s.led.SetColor("")
+          case constants.SENSOR_FLASH:
+            times, err := strconv.Atoi(string(evt.Payload))
+            if err != nil {
+              s.Printf("invalid sensor flash count %s: %s", string(evt.Payload), err)
+              continue
+            }
+            s.Flash(times, RGB{255, 255, 255})
+          case constants.SENSOR_COUNTDOWN:
+            // a white flash for each second left, then green to go
+            if string(evt.Payload) == "0" {
+              s.Flash(1, RGB{0, 255, 0})
+            } else {
+              s.Flash(1, RGB{255, 255, 255})
+            }
+          case constants.SENSOR_PAUSED:
+            s.Printf("game paused")
+            if paused == nil {
+              paused = time.NewTicker(constants.PAUSED_BLINK_INTERVAL)
+              pausedblink = paused.C
+            }
+          case constants.SENSOR_RESUMED:
+            s.Printf("game resumed")
+            if paused != nil {
+              paused.Stop()
+              paused = nil
+              pausedblink = nil
+            }
           default:
             s.Printf("unrecognized sensor event: %s", evt)
         }
@@ -113,11 +160,27 @@ func (s *Sensor) Close() {
   }
 }
 
+// Flash blinks whichever LED or LED strip the sensor has
+func (s *Sensor) Flash(times int, color RGB) {
+  if s.led.Connected() {
+    s.led.Blink(times)
+  }
+  if s.ledstrip.Connected() {
+    if err := s.ledstrip.Blink(times, color); err != nil {
+      s.Printf("error blinking LED strip: %s", err)
+    }
+  }
+}
+
 func (s *Sensor) SensorHit(sensorid string) {
   if !s.IsTestSensor() {
     s.led.Blink(1)
   }
 
+  s.lock.Lock()
+  s.lasthit = time.Now()
+  s.lock.Unlock()
+
   pay := strings.Join([]string{sensorid, s.led.GetColor(), "1"}, constants.SPLIT)
   s.Printf("preparing to sent sensor hit event...")
   select {
@@ -125,6 +188,30 @@ func (s *Sensor) SensorHit(sensorid string) {
       s.Printf("successfully sent sensor hit event: %s", pay)
     default:
       s.Printf("game chan is full - discarding event sensor hit")
+      s.Dropped.Add(constants.DROPPED_GAME_CHAN)
+  }
+}
+
+// Health reports the state of the sensor's hardware for a node:health query
+func (s *Sensor) Health() game.SensorHealth {
+  s.lock.Lock()
+  lasthit := s.lasthit
+  s.lock.Unlock()
+
+  dropped := s.Dropped.Counts()
+  if len(dropped) == 0 {
+    dropped = nil
+  }
+
+  return game.SensorHealth{
+    Id:       s.id,
+    Hits:     s.HitEnabled(),
+    HitLine:  s.hit.Held(),
+    Leds:     s.LedEnabled(),
+    Led:      s.led.Connected(),
+    LedStrip: s.ledstrip.Connected(),
+    LastHit:  lasthit,
+    Dropped:  dropped,
   }
 }
 
@@ -17,6 +17,7 @@ export const sendaction = (action, payload) => {
 export const uuid = writable("current")
 export const scoreboard = writable({})
 export const nodeboard = writable({})
+export const hitboard = writable({})
 export const nodes = writable([])
 export const teams = writable([])
 export const gameevents = writable([])
@@ -61,6 +62,7 @@ export async function fetchGame(id) {
     }))
     scoreboard.update(() => data.stats.scoreboard)
     nodeboard.update(() => data.stats.nodeboard)
+    hitboard.update(() => data.stats.hitboard)
     nodes.update(() => data.stats.nodes)
     teams.update(() => data.stats.teams)
     gameevents.update(() => data.stats.events)